@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"gamification-api/backend/database"
+	"gamification-api/backend/integrations/confluence"
+	"gamification-api/backend/models"
+	"log"
+)
+
+// runBackfillCommand hanterar underkommandot "backfill".
+//
+//	backend backfill -space DOCS -from 2023-01-01 -to 2024-12-31
+//	backend backfill -resume 7
+func runBackfillCommand(args []string, backfiller *confluence.Backfiller, jobRepo *database.BackfillRepository) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	spaceKey := flags.String("space", "", "Confluence-space att läsa in")
+	from := flags.String("from", "", "första datum som räknas (YYYY-MM-DD), tomt för obegränsat")
+	to := flags.String("to", "", "sista datum som räknas (YYYY-MM-DD), tomt för obegränsat")
+	resume := flags.Int64("resume", 0, "ID för ett avbrutet jobb som ska fortsätta")
+	flags.Parse(args)
+
+	if *resume > 0 {
+		log.Printf("Återupptar backfill-jobb %d...", *resume)
+		return backfiller.Run(*resume)
+	}
+
+	if *spaceKey == "" {
+		return errors.New("-space eller -resume måste anges")
+	}
+
+	job := &models.BackfillJob{SpaceKey: *spaceKey}
+	var err error
+	if job.From, err = models.ParseBackfillDate(*from, false); err != nil {
+		return err
+	}
+	if job.To, err = models.ParseBackfillDate(*to, true); err != nil {
+		return err
+	}
+
+	if err := jobRepo.CreateJob(job); err != nil {
+		return err
+	}
+	log.Printf("Skapade backfill-jobb %d. Om det avbryts, fortsätt med: backfill -resume %d", job.ID, job.ID)
+
+	return backfiller.Run(job.ID)
+}
@@ -0,0 +1,103 @@
+// Package badges utvärderar badge-villkor. Samma motor används när aktiviteter registreras
+// och när alla användare utvärderas om, t.ex. efter en backfill.
+package badges
+
+import (
+	"errors"
+	"fmt"
+	"gamification-api/backend/models"
+)
+
+// metricActivityTypes är aktivitetstyperna som varje räknare bygger på, samma som i user_stats.
+var metricActivityTypes = map[string][]string{
+	models.BadgeMetricComments:         {models.ActivityTypeCommentCreated},
+	models.BadgeMetricCreatedPages:     {models.ActivityTypePageCreated, models.ActivityTypeDocCreated},
+	models.BadgeMetricEditsMade:        {models.ActivityTypePageUpdated, models.ActivityTypeDocUpdated},
+	models.BadgeMetricResolvedComments: {models.ActivityTypeResolvedComment},
+}
+
+// maxDepth begränsar hur djupt villkor får nästlas.
+const maxDepth = 5
+
+// Validate kontrollerar att villkoren går att utvärdera.
+func Validate(c *models.BadgeCriteria) error {
+	return validate(c, 0)
+}
+
+func validate(c *models.BadgeCriteria, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("criteria must not be nested more than %d levels", maxDepth)
+	}
+
+	set := 0
+	for _, ok := range []bool{c.All != nil, c.Any != nil, c.Metric != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("each criterion must have exactly one of all, any or metric")
+	}
+
+	children := c.All
+	if c.Any != nil {
+		children = c.Any
+	}
+	if c.Metric == "" {
+		if len(children) == 0 {
+			return errors.New("all and any must contain at least one criterion")
+		}
+		for i := range children {
+			if err := validate(&children[i], depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if c.Min <= 0 {
+		return fmt.Errorf("min must be positive for metric %s", c.Metric)
+	}
+	if c.WindowDays < 0 {
+		return errors.New("windowDays must not be negative")
+	}
+	if c.Metric == models.BadgeMetricLifetimePoints {
+		if c.SpaceKey != "" || c.WindowDays != 0 {
+			return errors.New("lifetime_points cannot be combined with spaceKey or windowDays")
+		}
+		return nil
+	}
+	if c.Metric == models.BadgeMetricDailyStreak || c.Metric == models.BadgeMetricWeeklyStreak {
+		if c.WindowDays != 0 {
+			return fmt.Errorf("%s cannot be combined with windowDays", c.Metric)
+		}
+		return nil
+	}
+	if _, ok := metricActivityTypes[c.Metric]; !ok {
+		return fmt.Errorf("unknown metric: %s", c.Metric)
+	}
+	return nil
+}
+
+// CriteriaFor är villkoren för en badge. Badges utan criteria får ett villkor från
+// criteria_type och criteria_value. Returnerar nil för badges som inte delas ut
+// automatiskt, t.ex. tävlingspriser.
+func CriteriaFor(b *models.Badge) *models.BadgeCriteria {
+	if b.Criteria != nil {
+		return b.Criteria
+	}
+	c := &models.BadgeCriteria{Metric: b.CriteriaType, Min: b.CriteriaValue}
+	if Validate(c) != nil {
+		return nil
+	}
+	return c
+}
+
+// Summary är criteria_type och criteria_value som motsvarar villkoren, så att de gamla
+// kolumnerna stämmer. Sammansatta villkor räknas i procent.
+func Summary(c *models.BadgeCriteria) (string, int) {
+	if c.Metric != "" && c.SpaceKey == "" && c.WindowDays == 0 {
+		return c.Metric, c.Min
+	}
+	return models.BadgeCriteriaComposite, 100
+}
@@ -0,0 +1,104 @@
+package badges
+
+import (
+	"gamification-api/backend/models"
+	"testing"
+)
+
+func metric(name string, min int) models.BadgeCriteria {
+	return models.BadgeCriteria{Metric: name, Min: min}
+}
+
+func TestValidate(t *testing.T) {
+	nested := metric(models.BadgeMetricComments, 1)
+	for i := 0; i < maxDepth; i++ {
+		nested = models.BadgeCriteria{All: []models.BadgeCriteria{nested}}
+	}
+	tooDeep := models.BadgeCriteria{Any: []models.BadgeCriteria{nested}}
+
+	tests := []struct {
+		name     string
+		criteria models.BadgeCriteria
+		wantErr  bool
+	}{
+		{"mått", metric(models.BadgeMetricComments, 5), false},
+		{"mått i ett space och tidsfönster",
+			models.BadgeCriteria{Metric: models.BadgeMetricEditsMade, Min: 3, SpaceKey: "DOC", WindowDays: 7}, false},
+		{"svit i ett space",
+			models.BadgeCriteria{Metric: models.BadgeMetricDailyStreak, Min: 5, SpaceKey: "DOC"}, false},
+		{"all och any", models.BadgeCriteria{All: []models.BadgeCriteria{
+			metric(models.BadgeMetricLifetimePoints, 100),
+			{Any: []models.BadgeCriteria{metric(models.BadgeMetricWeeklyStreak, 4), metric(models.BadgeMetricCreatedPages, 10)}},
+		}}, false},
+		{"högsta nästlingen", nested, false},
+
+		{"tomt villkor", models.BadgeCriteria{}, true},
+		{"både all och mått", models.BadgeCriteria{All: []models.BadgeCriteria{metric(models.BadgeMetricComments, 1)}, Metric: models.BadgeMetricComments, Min: 1}, true},
+		{"tom all", models.BadgeCriteria{All: []models.BadgeCriteria{}}, true},
+		{"för djup nästling", tooDeep, true},
+		{"ogiltigt delvillkor", models.BadgeCriteria{Any: []models.BadgeCriteria{metric(models.BadgeMetricComments, 0)}}, true},
+		{"min noll", metric(models.BadgeMetricComments, 0), true},
+		{"negativt tidsfönster", models.BadgeCriteria{Metric: models.BadgeMetricComments, Min: 1, WindowDays: -1}, true},
+		{"poäng i ett space", models.BadgeCriteria{Metric: models.BadgeMetricLifetimePoints, Min: 1, SpaceKey: "DOC"}, true},
+		{"poäng i ett tidsfönster", models.BadgeCriteria{Metric: models.BadgeMetricLifetimePoints, Min: 1, WindowDays: 7}, true},
+		{"svit i ett tidsfönster", models.BadgeCriteria{Metric: models.BadgeMetricWeeklyStreak, Min: 1, WindowDays: 7}, true},
+		{"okänt mått", metric("total_likes", 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.criteria); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCriteriaFor(t *testing.T) {
+	composite := &models.BadgeCriteria{Any: []models.BadgeCriteria{metric(models.BadgeMetricComments, 1)}}
+
+	tests := []struct {
+		name  string
+		badge models.Badge
+		want  *models.BadgeCriteria
+	}{
+		{"egna villkor", models.Badge{CriteriaType: models.BadgeCriteriaComposite, CriteriaValue: 100, Criteria: composite}, composite},
+		{"gamla kolumnerna", models.Badge{CriteriaType: models.BadgeMetricComments, CriteriaValue: 5}, &models.BadgeCriteria{Metric: models.BadgeMetricComments, Min: 5}},
+		{"manuell", models.Badge{CriteriaType: models.BadgeCriteriaManual, CriteriaValue: 1}, nil},
+		{"okänd typ", models.Badge{CriteriaType: "competition_winner", CriteriaValue: 1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CriteriaFor(&tt.badge)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("CriteriaFor() = %+v, want nil", *got)
+			case tt.want != nil && (got == nil || got.Metric != tt.want.Metric || got.Min != tt.want.Min || len(got.Any) != len(tt.want.Any)):
+				t.Errorf("CriteriaFor() = %+v, want %+v", got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria models.BadgeCriteria
+		wantType string
+		wantMin  int
+	}{
+		{"mått", metric(models.BadgeMetricEditsMade, 10), models.BadgeMetricEditsMade, 10},
+		{"mått i ett space", models.BadgeCriteria{Metric: models.BadgeMetricEditsMade, Min: 10, SpaceKey: "DOC"}, models.BadgeCriteriaComposite, 100},
+		{"mått i ett tidsfönster", models.BadgeCriteria{Metric: models.BadgeMetricEditsMade, Min: 10, WindowDays: 7}, models.BadgeCriteriaComposite, 100},
+		{"all", models.BadgeCriteria{All: []models.BadgeCriteria{metric(models.BadgeMetricEditsMade, 10)}}, models.BadgeCriteriaComposite, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotType, gotMin := Summary(&tt.criteria); gotType != tt.wantType || gotMin != tt.wantMin {
+				t.Errorf("Summary() = %s, %d, want %s, %d", gotType, gotMin, tt.wantType, tt.wantMin)
+			}
+		})
+	}
+}
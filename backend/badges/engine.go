@@ -0,0 +1,367 @@
+package badges
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"gamification-api/backend/streaks"
+	"log"
+	"sort"
+)
+
+// Progress är hur långt en användare har kommit mot villkoren för en badge. För ett enskilt
+// mått är Value måttet och Target gränsen, för sammansatta villkor är det procent av 100.
+type Progress struct {
+	Value  int  `json:"value"`
+	Target int  `json:"target"`
+	Met    bool `json:"met"`
+}
+
+// percent är hur många procent av målet som är uppnått, högst 100.
+func (p Progress) percent() int {
+	if p.Target <= 0 || p.Value >= p.Target {
+		return 100
+	}
+	return p.Value * 100 / p.Target
+}
+
+// Engine utvärderar badge-villkor och delar ut badges.
+type Engine struct {
+	BadgeRepo     *database.BadgeRepository
+	UserBadgeRepo *database.UserBadgeRepository
+	MetricRepo    *database.BadgeMetricRepository
+	UserRepo      *database.UserRepository
+	// Streaks behövs för badges som bygger på sviter. Utan den räknas sviterna som 0
+	Streaks *streaks.Tracker
+}
+
+// metrics hämtar måtten för en användare. Räknarna i user_stats hämtas en gång och
+// räkningar per space eller tidsfönster sparas, så att samma mått bara frågas efter en gång.
+type metrics struct {
+	repo    *database.BadgeMetricRepository
+	streaks *streaks.Tracker
+	userID  int64
+	totals  map[string]int
+	counts  map[countKey]int
+	// streaksBySpace är sviterna per space, "" för alla spaces
+	streaksBySpace map[string]*models.UserStreaks
+}
+
+type countKey struct {
+	metric     string
+	spaceKey   string
+	windowDays int
+}
+
+func (e *Engine) metricsFor(userID int64) *metrics {
+	return &metrics{
+		repo:           e.MetricRepo,
+		streaks:        e.Streaks,
+		userID:         userID,
+		counts:         map[countKey]int{},
+		streaksBySpace: map[string]*models.UserStreaks{},
+	}
+}
+
+func (m *metrics) value(c *models.BadgeCriteria) (int, error) {
+	switch c.Metric {
+	case models.BadgeMetricDailyStreak, models.BadgeMetricWeeklyStreak:
+		return m.streak(c)
+	}
+
+	if c.SpaceKey == "" && c.WindowDays == 0 {
+		if m.totals == nil {
+			totals, err := m.repo.GetUserTotals(m.userID)
+			if err != nil {
+				return 0, err
+			}
+			m.totals = totals
+		}
+		return m.totals[c.Metric], nil
+	}
+
+	key := countKey{c.Metric, c.SpaceKey, c.WindowDays}
+	if n, ok := m.counts[key]; ok {
+		return n, nil
+	}
+	n, err := m.repo.CountActivities(m.userID, metricActivityTypes[c.Metric], c.SpaceKey, c.WindowDays)
+	if err != nil {
+		return 0, err
+	}
+	m.counts[key] = n
+	return n, nil
+}
+
+func (m *metrics) streak(c *models.BadgeCriteria) (int, error) {
+	if m.streaks == nil {
+		return 0, nil
+	}
+	s, ok := m.streaksBySpace[c.SpaceKey]
+	if !ok {
+		var err error
+		if s, err = m.streaks.ForUser(m.userID, c.SpaceKey); err != nil {
+			return 0, err
+		}
+		m.streaksBySpace[c.SpaceKey] = s
+	}
+	if c.Metric == models.BadgeMetricWeeklyStreak {
+		return s.Weekly.Longest, nil
+	}
+	return s.Daily.Longest, nil
+}
+
+// evaluate räknar ut hur långt användaren har kommit. För all är det medelvärdet av
+// delvillkorens procent och för any det bästa delvillkoret.
+func (e *Engine) evaluate(c *models.BadgeCriteria, m *metrics) (Progress, error) {
+	if c.Metric != "" {
+		v, err := m.value(c)
+		if err != nil {
+			return Progress{}, err
+		}
+		return Progress{Value: v, Target: c.Min, Met: v >= c.Min}, nil
+	}
+
+	children, all := c.Any, false
+	if c.All != nil {
+		children, all = c.All, true
+	}
+
+	result := Progress{Target: 100, Met: all}
+	var sum int
+	for i := range children {
+		p, err := e.evaluate(&children[i], m)
+		if err != nil {
+			return Progress{}, err
+		}
+		if all {
+			result.Met = result.Met && p.Met
+			sum += p.percent()
+		} else {
+			result.Met = result.Met || p.Met
+			if p.percent() > result.Value {
+				result.Value = p.percent()
+			}
+		}
+	}
+	if all {
+		result.Value = sum / len(children)
+	}
+	if result.Met {
+		result.Value = 100
+	}
+	return result, nil
+}
+
+// CheckUser utvärderar alla automatiska badges som användaren inte redan har, sparar
+// progress och låser upp de badges vars villkor är uppfyllda. Returnerar de som låstes upp.
+func (e *Engine) CheckUser(userID int64) ([]models.Badge, error) {
+	all, err := e.BadgeRepo.GetAllBadges()
+	if err != nil {
+		return nil, err
+	}
+	return e.checkUser(userID, all)
+}
+
+func (e *Engine) checkUser(userID int64, all []models.Badge) ([]models.Badge, error) {
+	owned, err := e.UserBadgeRepo.GetUserBadgesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	awarded := map[int64]bool{}
+	progress := map[int64]int{}
+	for _, ub := range owned {
+		if ub.AwardedAt != nil {
+			awarded[ub.BadgeID] = true
+		}
+		progress[ub.BadgeID] = ub.Progress
+	}
+
+	m := e.metricsFor(userID)
+	var unlocked []models.Badge
+	for _, b := range all {
+		c := CriteriaFor(&b)
+		if c == nil || awarded[b.ID] {
+			continue
+		}
+
+		p, err := e.evaluate(c, m)
+		if err != nil {
+			return unlocked, err
+		}
+		if prev, ok := progress[b.ID]; ok && prev == p.Value && !p.Met {
+			continue
+		}
+		if err := e.UserBadgeRepo.SaveProgress(userID, b.ID, p.Value, p.Met); err != nil {
+			return unlocked, err
+		}
+		if p.Met {
+			log.Printf("User %d awarded badge %d (%s)", userID, b.ID, b.Name)
+			unlocked = append(unlocked, b)
+		}
+	}
+	return unlocked, nil
+}
+
+// CheckAllUsers kör CheckUser för alla användare och returnerar hur många badges som låstes upp.
+func (e *Engine) CheckAllUsers() (int, error) {
+	all, err := e.BadgeRepo.GetAllBadges()
+	if err != nil {
+		return 0, err
+	}
+	users, err := e.UserRepo.GetAllUsers()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, u := range users {
+		unlocked, err := e.checkUser(u.ID, all)
+		count += len(unlocked)
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Reevaluate räknar om alla automatiska badges för alla användare, även de som redan är upplåsta.
+// Nya badges låses upp och badges vars villkor inte längre uppfylls tas tillbaka om de är
+// revocable, annars behålls de. En behållen badge rapporteras bara i den körning där villkoren
+// först slutar uppfyllas. Med dryRun sparas ingenting, ändringarna returneras bara.
+// Vid fel returneras de ändringar som hann göras.
+func (e *Engine) Reevaluate(dryRun bool) ([]models.BadgeChange, error) {
+	all, err := e.BadgeRepo.GetAllBadges()
+	if err != nil {
+		return nil, err
+	}
+	users, err := e.UserRepo.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.BadgeChange{}
+	for _, u := range users {
+		owned, err := e.UserBadgeRepo.GetUserBadgesByUserID(u.ID)
+		if err != nil {
+			return changes, err
+		}
+		current := map[int64]models.UserBadge{}
+		for _, ub := range owned {
+			current[ub.BadgeID] = ub
+		}
+
+		m := e.metricsFor(u.ID)
+		for _, b := range all {
+			c := CriteriaFor(&b)
+			if c == nil {
+				continue
+			}
+			p, err := e.evaluate(c, m)
+			if err != nil {
+				return changes, err
+			}
+
+			ub, exists := current[b.ID]
+			awarded := ub.AwardedAt != nil
+			grandfathered := !p.Met && awarded && !b.Revocable
+			change := models.BadgeChange{UserID: u.ID, BadgeID: b.ID, BadgeName: b.Name, Value: p.Value, Target: p.Target}
+			switch {
+			case p.Met && !awarded:
+				change.Change = models.BadgeChangeAwarded
+			case !p.Met && awarded && b.Revocable:
+				change.Change = models.BadgeChangeRevoked
+			case grandfathered && !ub.Grandfathered:
+				change.Change = models.BadgeChangeGrandfathered
+			}
+			if change.Change != "" {
+				changes = append(changes, change)
+			}
+			if dryRun {
+				continue
+			}
+
+			switch {
+			case change.Change == models.BadgeChangeRevoked:
+				err = e.UserBadgeRepo.RevokeBadge(u.ID, b.ID, p.Value)
+			case change.Change == models.BadgeChangeAwarded || !exists || ub.Progress != p.Value:
+				err = e.UserBadgeRepo.SaveProgress(u.ID, b.ID, p.Value, p.Met)
+			}
+			if err != nil {
+				return changes, err
+			}
+			// RevokeBadge tar själv bort markeringen
+			if exists && change.Change != models.BadgeChangeRevoked && grandfathered != ub.Grandfathered {
+				if err := e.UserBadgeRepo.SetGrandfathered(u.ID, b.ID, grandfathered); err != nil {
+					return changes, err
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// ProgressForUser räknar ut hur långt användaren har kommit mot varje badge, även de som inte
+// har någon rad i user_badges än. Badges i en serie grupperas och sorteras efter nivå.
+// Returnerar nil om användaren inte finns.
+func (e *Engine) ProgressForUser(userID int64) (*models.UserBadgeProgress, error) {
+	user, err := e.UserRepo.GetUserByID(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	all, err := e.BadgeRepo.GetAllBadges()
+	if err != nil {
+		return nil, err
+	}
+	owned, err := e.UserBadgeRepo.GetUserBadgesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	earned := map[int64]models.UserBadge{}
+	for _, ub := range owned {
+		if ub.AwardedAt != nil {
+			earned[ub.BadgeID] = ub
+		}
+	}
+
+	report := &models.UserBadgeProgress{Series: []models.BadgeSeriesProgress{}, Badges: []models.BadgeProgress{}}
+	seriesIndex := map[string]int{}
+	m := e.metricsFor(userID)
+	for _, b := range all {
+		ub := earned[b.ID]
+		bp := models.BadgeProgress{Badge: b, AwardedAt: ub.AwardedAt, Citation: ub.Citation}
+		bp.Earned = bp.AwardedAt != nil
+
+		if c := CriteriaFor(&b); c != nil {
+			p, err := e.evaluate(c, m)
+			if err != nil {
+				return nil, err
+			}
+			bp.Automatic = true
+			bp.Value, bp.Target, bp.Percent = p.Value, p.Target, p.percent()
+		}
+		if bp.Earned {
+			bp.Percent = 100
+		}
+
+		if b.Series == "" {
+			report.Badges = append(report.Badges, bp)
+			continue
+		}
+		i, ok := seriesIndex[b.Series]
+		if !ok {
+			i = len(report.Series)
+			seriesIndex[b.Series] = i
+			report.Series = append(report.Series, models.BadgeSeriesProgress{Series: b.Series})
+		}
+		s := &report.Series[i]
+		s.Badges = append(s.Badges, bp)
+		if bp.Earned && b.Tier > s.Tier {
+			s.Tier = b.Tier
+		}
+	}
+
+	for _, s := range report.Series {
+		sort.Slice(s.Badges, func(i, j int) bool { return s.Badges[i].Badge.Tier < s.Badges[j].Badge.Tier })
+	}
+	sort.Slice(report.Series, func(i, j int) bool { return report.Series[i].Series < report.Series[j].Series })
+	return report, nil
+}
@@ -0,0 +1,110 @@
+package badges
+
+import (
+	"gamification-api/backend/models"
+	"gamification-api/backend/streaks"
+	"testing"
+)
+
+// testMetrics har alla mått förifyllda, så att evaluate inte behöver någon databas.
+func testMetrics() *metrics {
+	return &metrics{
+		streaks: &streaks.Tracker{},
+		totals: map[string]int{
+			models.BadgeMetricComments:       5,
+			models.BadgeMetricEditsMade:      20,
+			models.BadgeMetricLifetimePoints: 300,
+		},
+		counts: map[countKey]int{
+			{models.BadgeMetricCreatedPages, "DOC", 0}: 2,
+			{models.BadgeMetricComments, "", 30}:       1,
+		},
+		streaksBySpace: map[string]*models.UserStreaks{
+			"": {Daily: models.Streak{Longest: 4}, Weekly: models.Streak{Longest: 2}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria models.BadgeCriteria
+		want     Progress
+	}{
+		{"uppfyllt mått", metric(models.BadgeMetricComments, 5), Progress{Value: 5, Target: 5, Met: true}},
+		{"ej uppfyllt mått", metric(models.BadgeMetricEditsMade, 50), Progress{Value: 20, Target: 50}},
+		{"mått som saknas", metric(models.BadgeMetricResolvedComments, 1), Progress{Value: 0, Target: 1}},
+		{"mått i ett space", models.BadgeCriteria{Metric: models.BadgeMetricCreatedPages, Min: 4, SpaceKey: "DOC"},
+			Progress{Value: 2, Target: 4}},
+		{"mått i ett tidsfönster", models.BadgeCriteria{Metric: models.BadgeMetricComments, Min: 1, WindowDays: 30},
+			Progress{Value: 1, Target: 1, Met: true}},
+		{"daglig svit", metric(models.BadgeMetricDailyStreak, 7), Progress{Value: 4, Target: 7}},
+		{"veckosvit", metric(models.BadgeMetricWeeklyStreak, 2), Progress{Value: 2, Target: 2, Met: true}},
+
+		{"all är medelvärdet", models.BadgeCriteria{All: []models.BadgeCriteria{
+			metric(models.BadgeMetricComments, 10),
+			metric(models.BadgeMetricEditsMade, 20),
+		}}, Progress{Value: 75, Target: 100}},
+		{"all uppfyllt", models.BadgeCriteria{All: []models.BadgeCriteria{
+			metric(models.BadgeMetricComments, 5),
+			metric(models.BadgeMetricLifetimePoints, 300),
+		}}, Progress{Value: 100, Target: 100, Met: true}},
+		{"any är det bästa", models.BadgeCriteria{Any: []models.BadgeCriteria{
+			metric(models.BadgeMetricEditsMade, 40),
+			{Metric: models.BadgeMetricCreatedPages, Min: 8, SpaceKey: "DOC"},
+		}}, Progress{Value: 50, Target: 100}},
+		{"any uppfyllt", models.BadgeCriteria{Any: []models.BadgeCriteria{
+			metric(models.BadgeMetricEditsMade, 40),
+			metric(models.BadgeMetricComments, 5),
+		}}, Progress{Value: 100, Target: 100, Met: true}},
+		{"nästlat", models.BadgeCriteria{All: []models.BadgeCriteria{
+			{Any: []models.BadgeCriteria{metric(models.BadgeMetricComments, 10), metric(models.BadgeMetricLifetimePoints, 300)}},
+			metric(models.BadgeMetricDailyStreak, 8),
+		}}, Progress{Value: 75, Target: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Engine{}).evaluate(&tt.criteria, testMetrics())
+			if err != nil {
+				t.Fatalf("evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateStreakWithoutTracker(t *testing.T) {
+	m := testMetrics()
+	m.streaks = nil
+	c := metric(models.BadgeMetricDailyStreak, 3)
+
+	got, err := (&Engine{}).evaluate(&c, m)
+	if err != nil {
+		t.Fatalf("evaluate() error = %v", err)
+	}
+	if want := (Progress{Value: 0, Target: 3}); got != want {
+		t.Errorf("evaluate() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		progress Progress
+		want     int
+	}{
+		{Progress{Value: 0, Target: 10}, 0},
+		{Progress{Value: 3, Target: 10}, 30},
+		{Progress{Value: 2, Target: 3}, 66},
+		{Progress{Value: 15, Target: 10}, 100},
+		{Progress{Value: 0, Target: 0}, 100},
+	}
+
+	for _, tt := range tests {
+		if got := tt.progress.percent(); got != tt.want {
+			t.Errorf("%+v.percent() = %d, want %d", tt.progress, got, tt.want)
+		}
+	}
+}
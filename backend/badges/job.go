@@ -0,0 +1,148 @@
+package badges
+
+import (
+	"errors"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrRunInProgress returneras när en omvärdering startas medan en annan körs.
+var ErrRunInProgress = errors.New("en omvärdering av badges körs redan")
+
+// Reevaluator räknar om alla badges för alla användare, schemalagt och när en admin
+// startar en körning. Behövs när villkoren för en badge har ändrats, eftersom Engine.CheckUser
+// bara tittar på badges som användaren inte redan har. Bara en körning görs åt gången.
+type Reevaluator struct {
+	Engine *Engine
+	Repo   *database.BadgeEvaluationRepository
+
+	mu      sync.Mutex
+	running bool
+	ticker  *time.Ticker
+	stop    chan bool
+}
+
+// NewReevaluator skapar en Reevaluator.
+func NewReevaluator(engine *Engine, repo *database.BadgeEvaluationRepository) *Reevaluator {
+	return &Reevaluator{
+		Engine: engine,
+		Repo:   repo,
+		stop:   make(chan bool),
+	}
+}
+
+// Start kör en omvärdering en gång per intervall. Första körningen görs efter ett intervall,
+// inte vid start. Med intervallet 0 görs inga schemalagda körningar, bara de som en admin startar.
+func (r *Reevaluator) Start(interval time.Duration) {
+	// Körningar som stod som running när servern stoppades blir aldrig klara
+	if err := r.Repo.FailInterruptedRuns(); err != nil {
+		log.Printf("FEL vid uppdatering av avbrutna omvärderingar: %v", err)
+	}
+	if interval <= 0 {
+		log.Println("Schemalagd omvärdering av badges är avstängd.")
+		return
+	}
+
+	log.Printf("Omvärdering av badges schemalagd var %v.", interval)
+	r.ticker = time.NewTicker(interval)
+
+	go func() {
+		for {
+			select {
+			case <-r.ticker.C:
+				r.runScheduled(models.BadgeEvaluationTriggerScheduled)
+			case <-r.stop:
+				r.ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop avslutar de schemalagda körningarna. En körning som pågår får bli klar.
+func (r *Reevaluator) Stop() {
+	if r.ticker == nil {
+		return
+	}
+	log.Println("Stoppar omvärderingen av badges...")
+	r.stop <- true
+}
+
+func (r *Reevaluator) runScheduled(trigger string) {
+	if _, err := r.start(trigger, false, nil); err != nil {
+		log.Printf("Omvärdering av badges (%s) kördes inte: %v", trigger, err)
+	}
+}
+
+// Trigger startar en omvärdering i bakgrunden och returnerar körningen direkt.
+func (r *Reevaluator) Trigger(dryRun bool, userID *int64) (*models.BadgeEvaluationRun, error) {
+	return r.start(models.BadgeEvaluationTriggerManual, dryRun, userID)
+}
+
+func (r *Reevaluator) start(trigger string, dryRun bool, userID *int64) (*models.BadgeEvaluationRun, error) {
+	if !r.claim() {
+		return nil, ErrRunInProgress
+	}
+
+	run := &models.BadgeEvaluationRun{Trigger: trigger, DryRun: dryRun, TriggeredByUserID: userID}
+	if err := r.Repo.CreateRun(run); err != nil {
+		r.release()
+		return nil, err
+	}
+
+	go func() {
+		defer r.release()
+		r.run(*run)
+	}()
+	return run, nil
+}
+
+func (r *Reevaluator) claim() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return false
+	}
+	r.running = true
+	return true
+}
+
+func (r *Reevaluator) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = false
+}
+
+func (r *Reevaluator) run(run models.BadgeEvaluationRun) {
+	log.Printf("Omvärdering av badges %d startar (%s, dry run: %v)", run.ID, run.Trigger, run.DryRun)
+
+	changes, err := r.Engine.Reevaluate(run.DryRun)
+	run.Changes = changes
+	for _, c := range changes {
+		switch c.Change {
+		case models.BadgeChangeAwarded:
+			run.Awarded++
+		case models.BadgeChangeRevoked:
+			run.Revoked++
+		case models.BadgeChangeGrandfathered:
+			run.Grandfathered++
+		}
+	}
+
+	run.Status = models.BadgeEvaluationStatusCompleted
+	if err != nil {
+		run.Status = models.BadgeEvaluationStatusFailed
+		run.Error = err.Error()
+		log.Printf("FEL vid omvärdering av badges %d: %v", run.ID, err)
+	}
+	if err := r.Repo.FinishRun(&run); err != nil {
+		log.Printf("FEL vid sparande av omvärdering %d: %v", run.ID, err)
+		return
+	}
+
+	log.Printf("Omvärdering av badges %d klar: %d upplåsta, %d borttagna, %d behållna.",
+		run.ID, run.Awarded, run.Revoked, run.Grandfathered)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,17 +16,152 @@ type Config struct {
 	ConfluenceEmail    string
 	ConfluenceAPIToken string
 	JWTSecret          string
+	// ConfluenceSpaceKeys är de spaces som alltid synkas. Fler kan läggas till via /api/v1/spaces.
+	ConfluenceSpaceKeys []string
+	// ConfluenceWebhookSecret signerar inkommande webhooks. Tomt betyder att webhooks är avstängda.
+	ConfluenceWebhookSecret string
+	// GitDocsRepos är sökvägar till lokala Git-repon vars Markdown/AsciiDoc-historik ger poäng
+	GitDocsRepos []string
 }
 
+// defaultConfluenceSpaceKey används om CONFLUENCE_SPACE_KEYS inte är satt.
+const defaultConfluenceSpaceKey = "teambfa0a452d69a428ba70ff3d22ef01502"
+
 func LoadConfig() *Config {
 	// Läser .env i aktuell arbetskatalog (ingen panik om filen saknas)
 	_ = godotenv.Load()
 
 	return &Config{
-		ConfluenceBaseURL:  mustGetEnv("CONFLUENCE_BASE_URL"),
-		ConfluenceEmail:    mustGetEnv("CONFLUENCE_EMAIL"),
-		ConfluenceAPIToken: mustGetEnv("CONFLUENCE_API_TOKEN"),
+		ConfluenceBaseURL:       mustGetEnv("CONFLUENCE_BASE_URL"),
+		ConfluenceEmail:         mustGetEnv("CONFLUENCE_EMAIL"),
+		ConfluenceAPIToken:      mustGetEnv("CONFLUENCE_API_TOKEN"),
+		ConfluenceSpaceKeys:     getListEnv("CONFLUENCE_SPACE_KEYS", []string{defaultConfluenceSpaceKey}),
+		ConfluenceWebhookSecret: os.Getenv("CONFLUENCE_WEBHOOK_SECRET"),
+		GitDocsRepos:            getListEnv("GIT_DOCS_REPOS", nil),
+	}
+}
+
+// SourceConfig är inställningarna för en aktivitetskälla.
+type SourceConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// LoadSourceConfig läser inställningarna för en aktivitetskälla från <NAMN>_SYNC_ENABLED
+// och <NAMN>_SYNC_INTERVAL (t.ex. GIT_SYNC_INTERVAL=5m). Källor är påslagna om inget annat anges.
+func LoadSourceConfig(name string, defaultInterval time.Duration) SourceConfig {
+	prefix := strings.ToUpper(name) + "_SYNC_"
+	sourceConfig := SourceConfig{Enabled: true, Interval: defaultInterval}
+
+	if v := os.Getenv(prefix + "ENABLED"); v != "" {
+		sourceConfig.Enabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv(prefix + "INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid duration in %sINTERVAL: %s", prefix, v)
+		}
+		sourceConfig.Interval = interval
+	}
+	return sourceConfig
+}
+
+// ModerationConfig är gränserna för missbruksskyddet. 0 stänger av en regel.
+type ModerationConfig struct {
+	// MinDiffSize är minsta storlek (diffBreakdown.size) för att en uppdatering ska räknas
+	MinDiffSize int
+	// MinCommentWords är minsta antal ord i en kommentar, så att "+1" och liknande inte ger poäng
+	MinCommentWords int
+	// MaxActivitiesPerHour är hur många aktiviteter en användare kan få poäng för per timme
+	MaxActivitiesPerHour int
+}
+
+// LoadModerationConfig läser MODERATION_MIN_DIFF_SIZE, MODERATION_MIN_COMMENT_WORDS
+// och MODERATION_MAX_ACTIVITIES_PER_HOUR.
+func LoadModerationConfig() ModerationConfig {
+	return ModerationConfig{
+		MinDiffSize:          getIntEnv("MODERATION_MIN_DIFF_SIZE", 20),
+		MinCommentWords:      getIntEnv("MODERATION_MIN_COMMENT_WORDS", 3),
+		MaxActivitiesPerHour: getIntEnv("MODERATION_MAX_ACTIVITIES_PER_HOUR", 30),
+	}
+}
+
+// PointCapConfig är taken för hur många poäng en användare kan få totalt, oavsett aktivitetstyp.
+// Tak per aktivitetstyp sätts i poängreglerna. 0 betyder inget tak.
+type PointCapConfig struct {
+	DailyCap  int
+	WeeklyCap int
+}
+
+// LoadPointCapConfig läser POINTS_DAILY_CAP och POINTS_WEEKLY_CAP.
+func LoadPointCapConfig() PointCapConfig {
+	return PointCapConfig{
+		DailyCap:  getIntEnv("POINTS_DAILY_CAP", 0),
+		WeeklyCap: getIntEnv("POINTS_WEEKLY_CAP", 0),
+	}
+}
+
+// StreakConfig är hur många dagar eller veckor utan aktivitet som får gå innan en svit bryts.
+type StreakConfig struct {
+	DailyGraceDays   int
+	WeeklyGraceWeeks int
+}
+
+// LoadStreakConfig läser STREAK_DAILY_GRACE_DAYS och STREAK_WEEKLY_GRACE_WEEKS. Som standard
+// bryter en helg inte en daglig svit.
+func LoadStreakConfig() StreakConfig {
+	return StreakConfig{
+		DailyGraceDays:   getIntEnv("STREAK_DAILY_GRACE_DAYS", 2),
+		WeeklyGraceWeeks: getIntEnv("STREAK_WEEKLY_GRACE_WEEKS", 0),
+	}
+}
+
+// BadgeConfig är inställningarna för omvärderingen av badges.
+type BadgeConfig struct {
+	// ReevaluationInterval är hur ofta alla badges räknas om. 0 stänger av den schemalagda körningen
+	ReevaluationInterval time.Duration
+}
+
+// LoadBadgeConfig läser BADGE_REEVALUATION_INTERVAL (t.ex. 12h). Som standard körs den en gång per dygn.
+func LoadBadgeConfig() BadgeConfig {
+	badgeConfig := BadgeConfig{ReevaluationInterval: 24 * time.Hour}
+	if v := os.Getenv("BADGE_REEVALUATION_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			log.Fatalf("Invalid duration in BADGE_REEVALUATION_INTERVAL: %s", v)
+		}
+		badgeConfig.ReevaluationInterval = interval
+	}
+	return badgeConfig
+}
+
+// getIntEnv läser ett heltal som inte får vara negativt.
+func getIntEnv(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid number in %s: %s", key, v)
+	}
+	return n
+}
+
+// getListEnv läser en kommaseparerad lista, t.ex. "DOCS,PRODUCT". Tomma värden ignoreras.
+func getListEnv(key string, fallback []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
 
 func mustGetEnv(key string) string {
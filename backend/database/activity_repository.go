@@ -2,9 +2,13 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"gamification-api/backend/models"
 	"log"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type ActivityRepository struct {
@@ -13,10 +17,10 @@ type ActivityRepository struct {
 
 // Hämtar alla aktiviteter från databasen
 func (r *ActivityRepository) GetAllActivities() ([]models.Activity, error) {
-	query := `SELECT id, user_id, confluence_page_id, confluence_version_number,
-	                 activity_type, points_awarded, created_at
+	query := `SELECT id, user_id, source, confluence_page_id, confluence_version_number,
+	                 activity_type, points_awarded, COALESCE(raw_points, points_awarded), scoring_rule_id, diff_breakdown, occurred_at, created_at, COALESCE(space_key, '')
 	          FROM activities
-	          ORDER BY created_at DESC`
+	          ORDER BY occurred_at DESC`
 
 	rows, err := r.DB.Query(query)
 	if err != nil {
@@ -28,15 +32,25 @@ func (r *ActivityRepository) GetAllActivities() ([]models.Activity, error) {
 
 	for rows.Next() {
 		var a models.Activity
+		var breakdown []byte
 		err := rows.Scan(
 			&a.ID,
 			&a.UserID,
+			&a.Source,
 			&a.ConfluencePageID,
 			&a.ConfluenceVersionNumber,
 			&a.ActivityType,
 			&a.PointsAwarded,
+			&a.RawPoints,
+			&a.ScoringRuleID,
+			&breakdown,
+			&a.OccurredAt,
 			&a.CreatedAt,
+			&a.SpaceKey,
 		)
+		if err == nil {
+			err = decodeDiffBreakdown(breakdown, &a)
+		}
 		if err != nil {
 			log.Println("Error scanning activity:", err)
 			continue
@@ -62,61 +76,251 @@ func (r *ActivityRepository) ActivityExists(pageID string, version int) (bool, e
 	return exists, nil
 }
 
+// ActivityExistsWithVersion kollar om en händelse redan är registrerad. Det motsvarar
+// unika nyckeln på activities (innehåll, version och aktivitetstyp).
+func (r *ActivityRepository) ActivityExistsWithVersion(contentID string, version int, activityType string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM activities
+		WHERE confluence_page_id = $1 AND confluence_version_number = $2 AND activity_type = $3)`,
+		contentID, version, activityType).Scan(&exists)
+	return exists, err
+}
+
+// GetLatestPageVersion returnerar det högsta versionsnumret som registrerats för en sida, eller 0.
+func (r *ActivityRepository) GetLatestPageVersion(pageID string) (int, error) {
+	var version int
+	err := r.DB.QueryRow(`
+		SELECT COALESCE(MAX(confluence_version_number), 0) FROM activities
+		WHERE confluence_page_id = $1 AND activity_type IN ('PAGE_CREATED', 'PAGE_UPDATED')`,
+		pageID).Scan(&version)
+	return version, err
+}
+
 // Hämta aktivitet efter ID
 func (r *ActivityRepository) GetActivityByID(id int64) (*models.Activity, error) {
 	row := r.DB.QueryRow(`
-		SELECT id, user_id, confluence_page_id, confluence_version_number,
-		       activity_type, points_awarded, created_at
+		SELECT id, user_id, source, confluence_page_id, confluence_version_number,
+		       activity_type, points_awarded, COALESCE(raw_points, points_awarded), scoring_rule_id, diff_breakdown, occurred_at, created_at, COALESCE(space_key, '')
 		FROM activities
 		WHERE id = $1`, id)
 
 	var a models.Activity
+	var breakdown []byte
 	err := row.Scan(
 		&a.ID,
 		&a.UserID,
+		&a.Source,
 		&a.ConfluencePageID,
 		&a.ConfluenceVersionNumber,
 		&a.ActivityType,
 		&a.PointsAwarded,
+		&a.RawPoints,
+		&a.ScoringRuleID,
+		&breakdown,
+		&a.OccurredAt,
 		&a.CreatedAt,
+		&a.SpaceKey,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := decodeDiffBreakdown(breakdown, &a); err != nil {
+		return nil, err
+	}
 	return &a, nil
 }
 
-// Skapa en ny aktivitet
-func (r *ActivityRepository) CreateActivity(a *models.Activity) (int64, error) {
+// decodeDiffBreakdown läser diff_breakdown, som är NULL för aktiviteter utan storlek.
+func decodeDiffBreakdown(data []byte, a *models.Activity) error {
+	if data == nil {
+		return nil
+	}
+	a.DiffBreakdown = &models.DiffBreakdown{}
+	return json.Unmarshal(data, a.DiffBreakdown)
+}
+
+// SumPoints räknar ihop en användares utdelade poäng och antal aktiviteter som skedde i
+// intervallet [from, to). Med en tom activityType räknas alla typer.
+func (r *ActivityRepository) SumPoints(userID int64, activityType string, from, to time.Time) (points int, count int, err error) {
+	err = r.DB.QueryRow(`
+		SELECT COALESCE(SUM(points_awarded), 0), COUNT(*) FROM activities
+		WHERE user_id = $1 AND ($2 = '' OR activity_type = $2) AND occurred_at >= $3 AND occurred_at < $4`,
+		userID, activityType, from, to).Scan(&points, &count)
+	return points, count, err
+}
+
+// GetActiveDays hämtar de dagar (UTC) då användaren hade minst en aktivitet, äldst först,
+// eventuellt bara i ett space. Flaggade aktiviteter räknas inte förrän de har godkänts.
+func (r *ActivityRepository) GetActiveDays(userID int64, spaceKey string) ([]time.Time, error) {
+	rows, err := r.DB.Query(`
+		SELECT DISTINCT (a.occurred_at AT TIME ZONE 'UTC')::date AS day
+		FROM activities a
+		WHERE a.user_id = $1 AND ($2 = '' OR a.space_key = $2)
+		  AND NOT EXISTS (SELECT 1 FROM activity_flags f WHERE f.activity_id = a.id AND f.status <> 'approved')
+		ORDER BY day`, userID, spaceKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// Skapa en ny aktivitet. Är entry satt skrivs den till poängliggaren i samma transaktion,
+// kopplad till aktiviteten, så att aktiviteten aldrig finns utan sina poäng i liggaren.
+func (r *ActivityRepository) CreateActivity(a *models.Activity, entry *models.LedgerEntry) (int64, error) {
 	var id int64
-	err := r.DB.QueryRow(`
-		INSERT INTO activities (user_id, confluence_page_id, confluence_version_number, activity_type, points_awarded, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+	now := time.Now().UTC()
+	// Saknas händelsetid (t.ex. manuellt skapade aktiviteter) räknas den som nu
+	occurredAt := a.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = now
+	}
+	if a.Source == "" {
+		a.Source = "confluence"
+	}
+	// Manuella aktiviteter har inga tak, där är råpoängen samma som de utdelade
+	if a.RawPoints == 0 {
+		a.RawPoints = a.PointsAwarded
+	}
+	// NULL om aktiviteten inte har någon storlek
+	var breakdown interface{}
+	if a.DiffBreakdown != nil {
+		data, err := json.Marshal(a.DiffBreakdown)
+		if err != nil {
+			return 0, err
+		}
+		breakdown = data
+	}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO activities (user_id, source, confluence_page_id, confluence_version_number, activity_type, points_awarded, raw_points, scoring_rule_id, diff_breakdown, content_hash, occurred_at, created_at, space_key, page_path)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12, NULLIF($13, ''), $14)
 		RETURNING id`,
-		a.UserID, a.ConfluencePageID, a.ConfluenceVersionNumber, a.ActivityType, a.PointsAwarded, time.Now().UTC(),
+		a.UserID, a.Source, a.ConfluencePageID, a.ConfluenceVersionNumber, a.ActivityType, a.PointsAwarded, a.RawPoints, a.ScoringRuleID, breakdown, a.ContentHash, occurredAt, now, a.SpaceKey, pq.Array(a.PagePath),
 	).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
-	return id, nil
+
+	if entry != nil {
+		entry.ActivityID = &id
+		if err := addLedgerEntry(tx, entry); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
 }
 
-// Uppdatera en aktivitet
-func (r *ActivityRepository) UpdateActivity(a *models.Activity) error {
-	_, err := r.DB.Exec(`
+// Uppdatera en aktivitet. Ändras poängen eller användaren förs skillnaden in i poängliggaren.
+// actorID är den som gjorde ändringen, nil om den är okänd.
+func (r *ActivityRepository) UpdateActivity(a *models.Activity, actorID *int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldUserID int64
+	var oldPoints int
+	var occurredAt time.Time
+	err = tx.QueryRow(`SELECT user_id, points_awarded, occurred_at FROM activities WHERE id = $1 FOR UPDATE`, a.ID).
+		Scan(&oldUserID, &oldPoints, &occurredAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		UPDATE activities
 		SET user_id = $1, confluence_page_id = $2, confluence_version_number = $3,
 		    activity_type = $4, points_awarded = $5
 		WHERE id = $6`,
 		a.UserID, a.ConfluencePageID, a.ConfluenceVersionNumber, a.ActivityType, a.PointsAwarded, a.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	var entries []models.LedgerEntry
+	if oldUserID != a.UserID {
+		// Aktiviteten har flyttats till en annan användare
+		entries = append(entries,
+			models.LedgerEntry{UserID: oldUserID, EntryType: models.LedgerEntryReversal, Points: -oldPoints, Reason: "Aktiviteten flyttades till en annan användare"},
+			models.LedgerEntry{UserID: a.UserID, EntryType: models.LedgerEntryAward, Points: a.PointsAwarded, Reason: a.ActivityType})
+	} else if a.PointsAwarded != oldPoints {
+		entries = append(entries,
+			models.LedgerEntry{UserID: a.UserID, EntryType: models.LedgerEntryAdjustment, Points: a.PointsAwarded - oldPoints, Reason: "Aktivitetens poäng ändrades"})
+	}
+	for _, e := range entries {
+		if e.Points == 0 {
+			continue
+		}
+		e.LifetimePoints = e.Points
+		e.ActivityID = &a.ID
+		e.ActorUserID = actorID
+		e.OccurredAt = occurredAt
+		if err := addLedgerEntry(tx, &e); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// Radera en aktivitet
-func (r *ActivityRepository) DeleteActivity(id int64) error {
-	_, err := r.DB.Exec(`DELETE FROM activities WHERE id = $1`, id)
-	return err
+// Radera en aktivitet. Poängen den gav återförs i poängliggaren.
+func (r *ActivityRepository) DeleteActivity(id int64, actorID *int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var points int
+	var occurredAt time.Time
+	err = tx.QueryRow(`SELECT user_id, points_awarded, occurred_at FROM activities WHERE id = $1 FOR UPDATE`, id).
+		Scan(&userID, &points, &occurredAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if points != 0 {
+		// activity_id blir NULL när aktiviteten raderas, så ID:t sparas i skälet
+		err := addLedgerEntry(tx, &models.LedgerEntry{
+			UserID:         userID,
+			EntryType:      models.LedgerEntryReversal,
+			Points:         -points,
+			LifetimePoints: -points,
+			Reason:         fmt.Sprintf("Aktivitet %d raderades", id),
+			ActorUserID:    actorID,
+			OccurredAt:     occurredAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM activities WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (repo *ActivityRepository) ActivityExistsWithType(contentID, activityType string) (bool, error) {
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"gamification-api/backend/models"
+)
+
+// BackfillRepository hanterar backfill-jobb och vilka sidor varje jobb redan har gått igenom.
+type BackfillRepository struct {
+	DB *sql.DB
+}
+
+const backfillJobColumns = `id, space_key, from_date, to_date, status, pages_done, activities_created,
+	COALESCE(error, ''), created_at, started_at, finished_at`
+
+func scanBackfillJob(row rowScanner) (*models.BackfillJob, error) {
+	var j models.BackfillJob
+	err := row.Scan(&j.ID, &j.SpaceKey, &j.From, &j.To, &j.Status, &j.PagesDone, &j.ActivitiesCreated,
+		&j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// GetAllJobs hämtar alla backfill-jobb, nyast först.
+func (r *BackfillRepository) GetAllJobs() ([]models.BackfillJob, error) {
+	rows, err := r.DB.Query(`SELECT ` + backfillJobColumns + ` FROM backfill_jobs ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.BackfillJob{}
+	for rows.Next() {
+		j, err := scanBackfillJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// GetJob hämtar ett backfill-jobb, eller nil om det inte finns.
+func (r *BackfillRepository) GetJob(id int64) (*models.BackfillJob, error) {
+	j, err := scanBackfillJob(r.DB.QueryRow(`SELECT `+backfillJobColumns+` FROM backfill_jobs WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return j, err
+}
+
+// CreateJob sparar ett nytt jobb med status pending.
+func (r *BackfillRepository) CreateJob(j *models.BackfillJob) error {
+	return r.DB.QueryRow(`
+		INSERT INTO backfill_jobs (space_key, from_date, to_date)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created_at`,
+		j.SpaceKey, j.From, j.To,
+	).Scan(&j.ID, &j.Status, &j.CreatedAt)
+}
+
+// SetStatus uppdaterar jobbets status. Starttiden sätts första gången jobbet körs och
+// sluttiden när det är klart eller har misslyckats.
+func (r *BackfillRepository) SetStatus(id int64, status, errorMessage string) error {
+	_, err := r.DB.Exec(`
+		UPDATE backfill_jobs
+		SET status = $2,
+		    error = NULLIF($3, ''),
+		    started_at = CASE WHEN $2 = 'running' THEN COALESCE(started_at, NOW()) ELSE started_at END,
+		    finished_at = CASE WHEN $2 IN ('completed', 'failed') THEN NOW() ELSE NULL END
+		WHERE id = $1`,
+		id, status, errorMessage)
+	return err
+}
+
+// ClaimJob markerar jobbet som running om ingen annan process kör det, så att samma jobb inte
+// körs två gånger samtidigt, t.ex. från kommandoraden och servern. Ett jobb vars heartbeat är
+// äldre än 30 minuter räknas som avbrutet och kan tas över. Returnerar false om jobbet inte
+// kunde tas, för att det körs, redan är klart eller inte finns.
+func (r *BackfillRepository) ClaimJob(id int64) (bool, error) {
+	res, err := r.DB.Exec(`
+		UPDATE backfill_jobs
+		SET status = 'running',
+		    error = NULL,
+		    started_at = COALESCE(started_at, NOW()),
+		    finished_at = NULL,
+		    heartbeat_at = NOW()
+		WHERE id = $1
+		  AND (status IN ('pending', 'failed')
+		       OR (status = 'running' AND (heartbeat_at IS NULL OR heartbeat_at < NOW() - INTERVAL '30 minutes')))`,
+		id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetDonePages hämtar ID:n för de sidor som jobbet redan har gått igenom.
+func (r *BackfillRepository) GetDonePages(jobID int64) (map[string]bool, error) {
+	rows, err := r.DB.Query(`SELECT page_id FROM backfill_job_pages WHERE job_id = $1`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[string]bool)
+	for rows.Next() {
+		var pageID string
+		if err := rows.Scan(&pageID); err != nil {
+			return nil, err
+		}
+		done[pageID] = true
+	}
+
+	return done, rows.Err()
+}
+
+// MarkPageDone markerar en sida som klar och räknar upp jobbets räknare. Det är jobbets checkpoint,
+// så ett avbrutet jobb fortsätter med nästa sida när det återupptas.
+func (r *BackfillRepository) MarkPageDone(jobID int64, pageID string, activitiesCreated int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO backfill_job_pages (job_id, page_id, activities_created)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (job_id, page_id) DO NOTHING`,
+		jobID, pageID, activitiesCreated)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := tx.Exec(`
+			UPDATE backfill_jobs
+			SET pages_done = pages_done + 1, activities_created = activities_created + $2, heartbeat_at = NOW()
+			WHERE id = $1`,
+			jobID, activitiesCreated); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gamification-api/backend/models"
+)
+
+// BadgeEvaluationRepository hanterar körningar som räknar om badges för alla användare.
+type BadgeEvaluationRepository struct {
+	DB *sql.DB
+}
+
+const badgeEvaluationColumns = `id, trigger, dry_run, status, awarded, revoked, grandfathered,
+	COALESCE(error, ''), triggered_by_user_id, started_at, finished_at`
+
+func scanBadgeEvaluationRun(row rowScanner, extra ...interface{}) (*models.BadgeEvaluationRun, error) {
+	var run models.BadgeEvaluationRun
+	var triggeredBy sql.NullInt64
+	dest := []interface{}{&run.ID, &run.Trigger, &run.DryRun, &run.Status, &run.Awarded, &run.Revoked, &run.Grandfathered,
+		&run.Error, &triggeredBy, &run.StartedAt, &run.FinishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if triggeredBy.Valid {
+		run.TriggeredByUserID = &triggeredBy.Int64
+	}
+	return &run, nil
+}
+
+// GetAllRuns hämtar alla körningar utan ändringslistan, nyast först.
+func (r *BadgeEvaluationRepository) GetAllRuns() ([]models.BadgeEvaluationRun, error) {
+	rows, err := r.DB.Query(`SELECT ` + badgeEvaluationColumns + ` FROM badge_evaluation_runs ORDER BY started_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []models.BadgeEvaluationRun{}
+	for rows.Next() {
+		run, err := scanBadgeEvaluationRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun hämtar en körning med alla ändringar. Returnerar nil om den inte finns.
+func (r *BadgeEvaluationRepository) GetRun(id int64) (*models.BadgeEvaluationRun, error) {
+	var changes []byte
+	run, err := scanBadgeEvaluationRun(r.DB.QueryRow(`SELECT `+badgeEvaluationColumns+`, changes
+		FROM badge_evaluation_runs WHERE id = $1`, id), &changes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &run.Changes); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// CreateRun sparar en ny körning med status running.
+func (r *BadgeEvaluationRepository) CreateRun(run *models.BadgeEvaluationRun) error {
+	return r.DB.QueryRow(`
+		INSERT INTO badge_evaluation_runs (trigger, dry_run, triggered_by_user_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, started_at`,
+		run.Trigger, run.DryRun, run.TriggeredByUserID,
+	).Scan(&run.ID, &run.Status, &run.StartedAt)
+}
+
+// FinishRun sparar status, räknare och ändringar när en körning är klar eller har misslyckats.
+func (r *BadgeEvaluationRepository) FinishRun(run *models.BadgeEvaluationRun) error {
+	changes, err := json.Marshal(run.Changes)
+	if err != nil {
+		return err
+	}
+	if run.Changes == nil {
+		changes = []byte("[]")
+	}
+
+	return r.DB.QueryRow(`
+		UPDATE badge_evaluation_runs
+		SET status = $2, awarded = $3, revoked = $4, grandfathered = $5, changes = $6, error = NULLIF($7, ''),
+		    finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at`,
+		run.ID, run.Status, run.Awarded, run.Revoked, run.Grandfathered, changes, run.Error,
+	).Scan(&run.FinishedAt)
+}
+
+// FailInterruptedRuns markerar körningar som fortfarande står som running som misslyckade.
+// Anropas vid start, då kan ingen körning vara igång och de har avbrutits av en omstart.
+func (r *BadgeEvaluationRepository) FailInterruptedRuns() error {
+	_, err := r.DB.Exec(`
+		UPDATE badge_evaluation_runs
+		SET status = 'failed', error = 'avbruten av omstart', finished_at = NOW()
+		WHERE status = 'running'`)
+	return err
+}
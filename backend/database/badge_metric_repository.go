@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"gamification-api/backend/models"
+
+	"github.com/lib/pq"
+)
+
+// BadgeMetricRepository hämtar måtten som badge-villkoren utvärderas mot.
+type BadgeMetricRepository struct {
+	DB *sql.DB
+}
+
+// GetUserTotals hämtar användarens räknare från user_stats och lifetime_points. Saknas
+// user_stats räknas allt som 0.
+func (r *BadgeMetricRepository) GetUserTotals(userID int64) (map[string]int, error) {
+	var comments, createdPages, edits, resolved, lifetimePoints int
+	err := r.DB.QueryRow(`
+		SELECT COALESCE(s.total_comments, 0), COALESCE(s.total_created_pages, 0), COALESCE(s.total_edits_made, 0),
+		       COALESCE(s.total_resolved_comments, 0), COALESCE(u.lifetime_points, 0)
+		FROM users u
+		LEFT JOIN user_stats s ON s.user_id = u.id
+		WHERE u.id = $1`, userID).Scan(&comments, &createdPages, &edits, &resolved, &lifetimePoints)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{
+		models.BadgeMetricComments:         comments,
+		models.BadgeMetricCreatedPages:     createdPages,
+		models.BadgeMetricEditsMade:        edits,
+		models.BadgeMetricResolvedComments: resolved,
+		models.BadgeMetricLifetimePoints:   lifetimePoints,
+	}, nil
+}
+
+// CountActivities räknar användarens aktiviteter av de givna typerna, eventuellt bara i ett space.
+// Med windowDays > 0 blir det det högsta antalet inom windowDays dagar i rad. Flaggade aktiviteter
+// räknas inte förrän de har godkänts, precis som i user_stats.
+func (r *BadgeMetricRepository) CountActivities(userID int64, activityTypes []string, spaceKey string, windowDays int) (int, error) {
+	filter := `
+		FROM activities a
+		WHERE a.user_id = $1 AND a.activity_type = ANY($2) AND ($3 = '' OR a.space_key = $3)
+		  AND NOT EXISTS (SELECT 1 FROM activity_flags f WHERE f.activity_id = a.id AND f.status <> 'approved')`
+
+	var count int
+	var err error
+	if windowDays <= 0 {
+		err = r.DB.QueryRow(`SELECT COUNT(*) `+filter, userID, pq.Array(activityTypes), spaceKey).Scan(&count)
+	} else {
+		// För varje aktivitet räknas de som skedde inom fönstret från och med den
+		err = r.DB.QueryRow(`
+			SELECT COALESCE(MAX(n), 0) FROM (
+				SELECT COUNT(*) OVER (
+				    ORDER BY a.occurred_at
+				    RANGE BETWEEN CURRENT ROW AND make_interval(days => $4) - INTERVAL '1 microsecond' FOLLOWING
+				) AS n `+filter+`
+			) w`, userID, pq.Array(activityTypes), spaceKey, windowDays).Scan(&count)
+	}
+	return count, err
+}
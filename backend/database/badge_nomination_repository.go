@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"gamification-api/backend/models"
+	"time"
+)
+
+var (
+	// ErrNominationExists returneras när samma person redan har en väntande nominering
+	// av samma kollega för samma badge.
+	ErrNominationExists = errors.New("nomineringen finns redan")
+	// ErrNominationReviewed returneras när en nominering som redan är granskad granskas igen.
+	ErrNominationReviewed = errors.New("nomineringen är redan granskad")
+)
+
+// BadgeNominationRepository hanterar nomineringar till manuella badges.
+type BadgeNominationRepository struct {
+	DB *sql.DB
+}
+
+const nominationColumns = `n.id, n.badge_id, b.name, n.nominee_user_id, nominee.display_name,
+	n.nominated_by_user_id, nominator.display_name, n.citation, n.status, n.reviewed_by_user_id, n.reviewed_at, n.created_at`
+
+const nominationJoins = `FROM badge_nominations n
+	JOIN badges b ON b.id = n.badge_id
+	JOIN users nominee ON nominee.id = n.nominee_user_id
+	JOIN users nominator ON nominator.id = n.nominated_by_user_id`
+
+func scanNomination(row rowScanner) (*models.BadgeNomination, error) {
+	var n models.BadgeNomination
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(&n.ID, &n.BadgeID, &n.BadgeName, &n.NomineeUserID, &n.NomineeName,
+		&n.NominatedByUserID, &n.NominatedByName, &n.Citation, &n.Status, &reviewedBy, &reviewedAt, &n.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedBy.Valid {
+		n.ReviewedByUserID = &reviewedBy.Int64
+	}
+	if reviewedAt.Valid {
+		n.ReviewedAt = &reviewedAt.Time
+	}
+	return &n, nil
+}
+
+// GetNominations hämtar nomineringar med en viss status, eller alla om status är tom. Äldst först, som en kö.
+func (r *BadgeNominationRepository) GetNominations(status string) ([]models.BadgeNomination, error) {
+	rows, err := r.DB.Query(`SELECT `+nominationColumns+` `+nominationJoins+`
+		WHERE $1 = '' OR n.status = $1
+		ORDER BY n.created_at, n.id`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nominations := []models.BadgeNomination{}
+	for rows.Next() {
+		n, err := scanNomination(rows)
+		if err != nil {
+			return nil, err
+		}
+		nominations = append(nominations, *n)
+	}
+	return nominations, rows.Err()
+}
+
+// GetNomination hämtar en nominering. Returnerar nil om den inte finns.
+func (r *BadgeNominationRepository) GetNomination(id int64) (*models.BadgeNomination, error) {
+	n, err := scanNomination(r.DB.QueryRow(`SELECT `+nominationColumns+` `+nominationJoins+` WHERE n.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return n, err
+}
+
+// CreateNomination sparar en ny nominering med status pending.
+func (r *BadgeNominationRepository) CreateNomination(n *models.BadgeNomination) error {
+	err := r.DB.QueryRow(`
+		INSERT INTO badge_nominations (badge_id, nominee_user_id, nominated_by_user_id, citation)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (badge_id, nominee_user_id, nominated_by_user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id, status, created_at`,
+		n.BadgeID, n.NomineeUserID, n.NominatedByUserID, n.Citation,
+	).Scan(&n.ID, &n.Status, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrNominationExists
+	}
+	return err
+}
+
+// ReviewNomination godkänner eller avvisar en nominering. Vid godkännande delas badgen ut med
+// nomineringens motivering, eller citation om den inte är tom. Har användaren redan badgen
+// returneras ErrBadgeAlreadyAwarded och nomineringen lämnas orörd.
+func (r *BadgeNominationRepository) ReviewNomination(id int64, approve bool, reviewerID int64, citation string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var badgeID, nomineeID int64
+	var status, nominationCitation string
+	err = tx.QueryRow(`SELECT badge_id, nominee_user_id, status, citation FROM badge_nominations WHERE id = $1 FOR UPDATE`, id).
+		Scan(&badgeID, &nomineeID, &status, &nominationCitation)
+	if err != nil {
+		return err
+	}
+	if status != models.NominationStatusPending {
+		return ErrNominationReviewed
+	}
+
+	newStatus := models.NominationStatusRejected
+	if approve {
+		newStatus = models.NominationStatusApproved
+		if citation == "" {
+			citation = nominationCitation
+		}
+		if _, err := grantBadge(tx, nomineeID, badgeID, citation, reviewerID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE badge_nominations SET status = $1, reviewed_by_user_id = $2, reviewed_at = $3 WHERE id = $4`,
+		newStatus, reviewerID, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"gamification-api/backend/models"
 	"log"
 )
@@ -15,9 +17,50 @@ type UserBadgeRepository struct {
 	DB *sql.DB
 }
 
+// ErrBadgeAlreadyAwarded returneras när en badge delas ut till någon som redan har den.
+var ErrBadgeAlreadyAwarded = errors.New("användaren har redan badgen")
+
+const badgeColumns = `id, name, description, icon_url, criteria_value, criteria_type, criteria, series, tier, revocable`
+
+func scanBadge(row rowScanner) (*models.Badge, error) {
+	var b models.Badge
+	var criteria []byte
+	if err := row.Scan(&b.ID, &b.Name, &b.Description, &b.IconUrl, &b.CriteriaValue, &b.CriteriaType, &criteria, &b.Series, &b.Tier, &b.Revocable); err != nil {
+		return nil, err
+	}
+	if criteria != nil {
+		if err := json.Unmarshal(criteria, &b.Criteria); err != nil {
+			return nil, err
+		}
+	}
+	return &b, nil
+}
+
+const userBadgeColumns = `user_id, badge_id, awarded_at, progress, COALESCE(citation, ''), awarded_by_user_id, grandfathered`
+
+func scanUserBadge(row rowScanner) (*models.UserBadge, error) {
+	var ub models.UserBadge
+	var awardedBy sql.NullInt64
+	if err := row.Scan(&ub.UserID, &ub.BadgeID, &ub.AwardedAt, &ub.Progress, &ub.Citation, &awardedBy, &ub.Grandfathered); err != nil {
+		return nil, err
+	}
+	if awardedBy.Valid {
+		ub.AwardedByUserID = &awardedBy.Int64
+	}
+	return &ub, nil
+}
+
+// encodeCriteria gör om villkoren till JSONB, eller NULL om badgen bara har criteria_type.
+func encodeCriteria(c *models.BadgeCriteria) (interface{}, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
 // Hämtar alla badges från db
 func (r *BadgeRepository) GetAllBadges() ([]models.Badge, error) {
-	query := `SELECT id, name, description, icon_url, criteria_value, criteria_type FROM badges ORDER BY name DESC` //ordern är just nu by name
+	query := `SELECT ` + badgeColumns + ` FROM badges ORDER BY name DESC` //ordern är just nu by name
 	rows, err := r.DB.Query(query)
 
 	if err != nil {
@@ -28,20 +71,12 @@ func (r *BadgeRepository) GetAllBadges() ([]models.Badge, error) {
 	var badges []models.Badge
 
 	for rows.Next() {
-		var badge models.Badge
-		err := rows.Scan(
-			&badge.ID,
-			&badge.Name,
-			&badge.Description,
-			&badge.IconUrl,
-			&badge.CriteriaValue,
-			&badge.CriteriaType,
-		)
+		badge, err := scanBadge(rows)
 		if err != nil {
 			log.Println("Error scanning badge:", err)
 			continue
 		}
-		badges = append(badges, badge)
+		badges = append(badges, *badge)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -51,12 +86,17 @@ func (r *BadgeRepository) GetAllBadges() ([]models.Badge, error) {
 
 // Skapa en ny badge
 func (r *BadgeRepository) CreateBadge(b *models.Badge) (int64, error) {
+	criteria, err := encodeCriteria(b.Criteria)
+	if err != nil {
+		return 0, err
+	}
+
 	var id int64
-	err := r.DB.QueryRow(`
-		INSERT INTO badges (name, description, icon_url, criteria_value, criteria_type)
-		VALUES ($1, $2, $3, $4, $5)
+	err = r.DB.QueryRow(`
+		INSERT INTO badges (name, description, icon_url, criteria_value, criteria_type, criteria, series, tier, revocable)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`,
-		b.Name, b.Description, b.IconUrl, b.CriteriaValue,
+		b.Name, b.Description, b.IconUrl, b.CriteriaValue, b.CriteriaType, criteria, b.Series, b.Tier, b.Revocable,
 	).Scan(&id)
 
 	if err != nil {
@@ -67,11 +107,17 @@ func (r *BadgeRepository) CreateBadge(b *models.Badge) (int64, error) {
 
 // Uppdatera en badge
 func (r *BadgeRepository) UpdateBadge(b *models.Badge) error {
-	_, err := r.DB.Exec(`
+	criteria, err := encodeCriteria(b.Criteria)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.Exec(`
 		UPDATE badges
-		SET name = $1, description = $2, icon_url = $3, criteria_value = $4, criteria_type = $5
-		WHERE id = $6`,
-		b.Name, b.Description, b.IconUrl, b.CriteriaValue, b.CriteriaType, b.ID,
+		SET name = $1, description = $2, icon_url = $3, criteria_value = $4, criteria_type = $5, criteria = $6,
+		    series = $7, tier = $8, revocable = $9
+		WHERE id = $10`,
+		b.Name, b.Description, b.IconUrl, b.CriteriaValue, b.CriteriaType, criteria, b.Series, b.Tier, b.Revocable, b.ID,
 	)
 	return err
 }
@@ -91,18 +137,7 @@ func (r *BadgeRepository) DeleteBadge(id int64) error {
 
 // Hämta badge efter ID
 func (r *BadgeRepository) GetBadgeByID(id int64) (*models.Badge, error) {
-	row := r.DB.QueryRow(`
-		SELECT id, name, description, icon_url, criteria_value, criteria_type
-		FROM badges
-		WHERE id = $1`, id,
-	)
-
-	var b models.Badge
-	err := row.Scan(&b.ID, &b.Name, &b.Description, &b.IconUrl, &b.CriteriaValue)
-	if err != nil {
-		return nil, err
-	}
-	return &b, nil
+	return scanBadge(r.DB.QueryRow(`SELECT `+badgeColumns+` FROM badges WHERE id = $1`, id))
 }
 
 // Ta bort en badge från en användare
@@ -117,7 +152,7 @@ func (r *UserBadgeRepository) RemoveBadge(userID, badgeID int64) error {
 
 // Hämta alla user_badges från db
 func (r *UserBadgeRepository) GetAllUserBadges() ([]models.UserBadge, error) {
-	query := `SELECT * FROM user_badges ORDER BY awarded_at DESC`
+	query := `SELECT ` + userBadgeColumns + ` FROM user_badges ORDER BY awarded_at DESC NULLS LAST`
 	rows, err := r.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -127,18 +162,12 @@ func (r *UserBadgeRepository) GetAllUserBadges() ([]models.UserBadge, error) {
 	var userBadges []models.UserBadge
 
 	for rows.Next() {
-		var ub models.UserBadge
-		err := rows.Scan(
-			&ub.UserID,
-			&ub.BadgeID,
-			&ub.AwardedAt,
-			&ub.Progress,
-		)
+		ub, err := scanUserBadge(rows)
 		if err != nil {
 			log.Println("Error scanning user_badge:", err)
 			continue
 		}
-		userBadges = append(userBadges, ub)
+		userBadges = append(userBadges, *ub)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -171,7 +200,7 @@ func (r *UserBadgeRepository) UpdateUserBadge(ub *models.UserBadge) error {
 
 // Hämta alla badges för en specifik användare
 func (r *UserBadgeRepository) GetUserBadgesByUserID(userID int64) ([]models.UserBadge, error) {
-	query := `SELECT user_id, badge_id, awarded_at, progress FROM user_badges WHERE user_id = $1 ORDER BY awarded_at DESC`
+	query := `SELECT ` + userBadgeColumns + ` FROM user_badges WHERE user_id = $1 ORDER BY awarded_at DESC NULLS LAST`
 	rows, err := r.DB.Query(query, userID)
 	if err != nil {
 		return nil, err
@@ -180,13 +209,12 @@ func (r *UserBadgeRepository) GetUserBadgesByUserID(userID int64) ([]models.User
 
 	var userBadges []models.UserBadge
 	for rows.Next() {
-		var ub models.UserBadge
-		err := rows.Scan(&ub.UserID, &ub.BadgeID, &ub.AwardedAt, &ub.Progress)
+		ub, err := scanUserBadge(rows)
 		if err != nil {
 			log.Println("Error scanning user_badge:", err)
 			continue
 		}
-		userBadges = append(userBadges, ub)
+		userBadges = append(userBadges, *ub)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -197,106 +225,82 @@ func (r *UserBadgeRepository) GetUserBadgesByUserID(userID int64) ([]models.User
 // Hämta en specifik user_badge (t.ex. via kombon user_id + badge_id)
 func (r *UserBadgeRepository) GetUserBadge(userID, badgeID int64) (*models.UserBadge, error) {
 	row := r.DB.QueryRow(`
-		SELECT user_id, badge_id, awarded_at, progress
+		SELECT `+userBadgeColumns+`
 		FROM user_badges
 		WHERE user_id = $1 AND badge_id = $2`,
 		userID, badgeID,
 	)
 
-	var ub models.UserBadge
-	err := row.Scan(&ub.UserID, &ub.BadgeID, &ub.AwardedAt, &ub.Progress)
+	ub, err := scanUserBadge(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Returnera nil om ingen badge hittades
 		}
 		return nil, err
 	}
-	return &ub, nil
+	return ub, nil
 }
 
-// CheckAndAwardBadges kontrollerar om en användare uppfyller kraven för nya badges och tilldelar dem.
-func (r *UserBadgeRepository) CheckAndAwardBadges(userID int64) error {
-	// 1. Hämta user_stats
-	var stats struct {
-		TotalComments         int
-		TotalCreatedPages     int
-		TotalEditsMade        int
-		TotalResolvedComments int
-	}
+// SaveProgress sparar hur långt användaren har kommit mot en badge. Med earned låses badgen upp,
+// en redan upplåst badge behåller sitt datum.
+func (r *UserBadgeRepository) SaveProgress(userID, badgeID int64, progress int, earned bool) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO user_badges (user_id, badge_id, progress, awarded_at)
+		VALUES ($1, $2, $3, CASE WHEN $4::boolean THEN NOW() END)
+		ON CONFLICT (user_id, badge_id) DO UPDATE SET
+		    progress = EXCLUDED.progress,
+		    awarded_at = COALESCE(user_badges.awarded_at, EXCLUDED.awarded_at)`,
+		userID, badgeID, progress, earned)
+	return err
+}
 
-	err := r.DB.QueryRow(`
-		SELECT total_comments, total_created_pages, total_edits_made, total_resolved_comments
-		FROM user_stats WHERE user_id = $1
-	`, userID).Scan(&stats.TotalComments, &stats.TotalCreatedPages, &stats.TotalEditsMade, &stats.TotalResolvedComments)
+// RevokeBadge tar tillbaka en upplåst badge. Raden behålls med progress så att den kan låsas upp igen.
+func (r *UserBadgeRepository) RevokeBadge(userID, badgeID int64, progress int) error {
+	_, err := r.DB.Exec(`UPDATE user_badges SET awarded_at = NULL, progress = $3, grandfathered = FALSE WHERE user_id = $1 AND badge_id = $2`,
+		userID, badgeID, progress)
+	return err
+}
 
-	if err != nil {
-		return err
-	}
+// SetGrandfathered markerar att en upplåst badge behålls fast villkoren inte längre uppfylls,
+// eller tar bort markeringen när villkoren uppfylls igen.
+func (r *UserBadgeRepository) SetGrandfathered(userID, badgeID int64, grandfathered bool) error {
+	_, err := r.DB.Exec(`UPDATE user_badges SET grandfathered = $3 WHERE user_id = $1 AND badge_id = $2`,
+		userID, badgeID, grandfathered)
+	return err
+}
 
-	// 2. Hämta alla badges
-	rows, err := r.DB.Query(`
-		SELECT id, criteria_type, criteria_value
-		FROM badges
-	`)
+// GrantBadge delar ut en manuell badge med en motivering. Returnerar ErrBadgeAlreadyAwarded
+// om användaren redan har badgen.
+func (r *UserBadgeRepository) GrantBadge(userID, badgeID int64, citation string, grantedBy int64) (*models.UserBadge, error) {
+	tx, err := r.DB.Begin()
 	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	type badgeCheck struct {
-		ID            int64
-		CriteriaType  string
-		CriteriaValue int
+		return nil, err
 	}
-	var allBadges []badgeCheck
+	defer tx.Rollback()
 
-	for rows.Next() {
-		var b badgeCheck
-		if err := rows.Scan(&b.ID, &b.CriteriaType, &b.CriteriaValue); err == nil {
-			allBadges = append(allBadges, b)
-		}
+	ub, err := grantBadge(tx, userID, badgeID, citation, grantedBy)
+	if err != nil {
+		return nil, err
 	}
+	return ub, tx.Commit()
+}
 
-	// 3. Gå igenom varje badge och kolla om användaren kvalificerar sig
-	for _, b := range allBadges {
-		var userValue int
-		switch b.CriteriaType {
-		case "total_comments":
-			userValue = stats.TotalComments
-		case "total_created_pages":
-			userValue = stats.TotalCreatedPages
-		case "total_edits_made":
-			userValue = stats.TotalEditsMade
-		case "total_resolved_comments":
-			userValue = stats.TotalResolvedComments
-		default:
-			continue
-		}
-
-		if userValue >= b.CriteriaValue {
-			// Kolla om användaren redan har badge:n
-			var exists bool
-			err := r.DB.QueryRow(`
-				SELECT EXISTS (
-					SELECT 1 FROM user_badges WHERE user_id = $1 AND badge_id = $2
-				)
-			`, userID, b.ID).Scan(&exists)
-			if err != nil {
-				return err
-			}
-
-			if !exists {
-				// Tilldela badge
-				_, err := r.DB.Exec(`
-					INSERT INTO user_badges (user_id, badge_id)
-					VALUES ($1, $2)
-				`, userID, b.ID)
-				if err != nil {
-					return err
-				}
-				log.Printf("User %d awarded badge %d\n", userID, b.ID)
-			}
-		}
+func grantBadge(tx *sql.Tx, userID, badgeID int64, citation string, grantedBy int64) (*models.UserBadge, error) {
+	// Finns raden redan utan awarded_at uppdateras den, är badgen redan upplåst ändras ingenting.
+	// Progress blir 1, samma som criteria_value för manuella badges.
+	ub, err := scanUserBadge(tx.QueryRow(`
+		INSERT INTO user_badges (user_id, badge_id, awarded_at, progress, citation, awarded_by_user_id)
+		VALUES ($1, $2, NOW(), 1, $3, $4)
+		ON CONFLICT (user_id, badge_id) DO UPDATE SET
+		    awarded_at = EXCLUDED.awarded_at,
+		    progress = EXCLUDED.progress,
+		    citation = EXCLUDED.citation,
+		    awarded_by_user_id = EXCLUDED.awarded_by_user_id
+		WHERE user_badges.awarded_at IS NULL
+		RETURNING `+userBadgeColumns,
+		userID, badgeID, citation, grantedBy))
+	if err == sql.ErrNoRows {
+		return nil, ErrBadgeAlreadyAwarded
 	}
-	return nil
+	return ub, err
 }
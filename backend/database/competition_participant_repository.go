@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"gamification-api/backend/models"
+)
+
+// CompetitionParticipantRepository hanterar anmälningar av användare och team till tävlingar.
+type CompetitionParticipantRepository struct {
+	DB *sql.DB
+}
+
+// GetParticipants hämtar alla deltagare (användare och team) i en tävling.
+func (r *CompetitionParticipantRepository) GetParticipants(competitionID int64) ([]models.CompetitionParticipant, error) {
+	rows, err := r.DB.Query(`
+		SELECT cp.id, cp.competition_id, cp.user_id, cp.team_id,
+		       COALESCE(u.display_name, t.name, ''), cp.status, cp.joined_at
+		FROM competition_participants cp
+		LEFT JOIN users u ON u.id = cp.user_id
+		LEFT JOIN teams t ON t.id = cp.team_id
+		WHERE cp.competition_id = $1
+		ORDER BY cp.joined_at ASC`, competitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := []models.CompetitionParticipant{}
+	for rows.Next() {
+		var p models.CompetitionParticipant
+		if err := rows.Scan(&p.ID, &p.CompetitionID, &p.UserID, &p.TeamID, &p.Name, &p.Status, &p.JoinedAt); err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
+	}
+
+	return participants, rows.Err()
+}
+
+// GetUserParticipant hämtar en användares egen anmälan till en tävling, eller nil om den saknas.
+func (r *CompetitionParticipantRepository) GetUserParticipant(competitionID, userID int64) (*models.CompetitionParticipant, error) {
+	return r.getParticipant(`cp.competition_id = $1 AND cp.user_id = $2`, competitionID, userID)
+}
+
+// GetTeamParticipant hämtar ett teams anmälan till en tävling, eller nil om den saknas.
+func (r *CompetitionParticipantRepository) GetTeamParticipant(competitionID, teamID int64) (*models.CompetitionParticipant, error) {
+	return r.getParticipant(`cp.competition_id = $1 AND cp.team_id = $2`, competitionID, teamID)
+}
+
+// GetParticipant hämtar en anmälan via dess ID, eller nil om den saknas.
+func (r *CompetitionParticipantRepository) GetParticipant(competitionID, participantID int64) (*models.CompetitionParticipant, error) {
+	return r.getParticipant(`cp.competition_id = $1 AND cp.id = $2`, competitionID, participantID)
+}
+
+func (r *CompetitionParticipantRepository) getParticipant(condition string, args ...interface{}) (*models.CompetitionParticipant, error) {
+	row := r.DB.QueryRow(`
+		SELECT cp.id, cp.competition_id, cp.user_id, cp.team_id,
+		       COALESCE(u.display_name, t.name, ''), cp.status, cp.joined_at
+		FROM competition_participants cp
+		LEFT JOIN users u ON u.id = cp.user_id
+		LEFT JOIN teams t ON t.id = cp.team_id
+		WHERE `+condition, args...)
+
+	var p models.CompetitionParticipant
+	err := row.Scan(&p.ID, &p.CompetitionID, &p.UserID, &p.TeamID, &p.Name, &p.Status, &p.JoinedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetUserStatus skapar eller uppdaterar en användares anmälan med den givna statusen.
+func (r *CompetitionParticipantRepository) SetUserStatus(competitionID, userID int64, status string) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO competition_participants (competition_id, user_id, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (competition_id, user_id) WHERE user_id IS NOT NULL
+		DO UPDATE SET status = EXCLUDED.status, joined_at = NOW()`,
+		competitionID, userID, status)
+	return err
+}
+
+// SetTeamStatus skapar eller uppdaterar ett teams anmälan med den givna statusen.
+func (r *CompetitionParticipantRepository) SetTeamStatus(competitionID, teamID int64, status string) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO competition_participants (competition_id, team_id, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (competition_id, team_id) WHERE team_id IS NOT NULL
+		DO UPDATE SET status = EXCLUDED.status, joined_at = NOW()`,
+		competitionID, teamID, status)
+	return err
+}
+
+// RemoveUser tar bort en användares anmälan helt, t.ex. när ett opt-out i en lagtävling ångras.
+func (r *CompetitionParticipantRepository) RemoveUser(competitionID, userID int64) error {
+	_, err := r.DB.Exec(`DELETE FROM competition_participants WHERE competition_id = $1 AND user_id = $2`, competitionID, userID)
+	return err
+}
+
+// RemoveParticipant tar bort en anmälan via dess ID.
+func (r *CompetitionParticipantRepository) RemoveParticipant(competitionID, participantID int64) error {
+	_, err := r.DB.Exec(`DELETE FROM competition_participants WHERE competition_id = $1 AND id = $2`, competitionID, participantID)
+	return err
+}
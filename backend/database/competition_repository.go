@@ -2,20 +2,62 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"gamification-api/backend/models"
+	"math"
+	"sort"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type CompetitionRepository struct {
 	DB *sql.DB
 }
 
+// competitionColumns är kolumnerna som scanCompetition förväntar sig, i samma ordning.
+const competitionColumns = `id, name, description, start_date, end_date, created_by_user_id, created_at,
+	competition_type, invite_only, prize_badge_id, prize_top_n, finalized_at, rules`
+
+// rowScanner täcker både *sql.Row och *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCompetition(row rowScanner) (*models.Competition, error) {
+	var (
+		c     models.Competition
+		rules []byte
+	)
+	err := row.Scan(
+		&c.ID,
+		&c.Name,
+		&c.Description,
+		&c.StartDate,
+		&c.EndDate,
+		&c.CreatedByUserID,
+		&c.CreatedAt,
+		&c.CompetitionType,
+		&c.InviteOnly,
+		&c.PrizeBadgeID,
+		&c.PrizeTopN,
+		&c.FinalizedAt,
+		&rules,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &c.Rules); err != nil {
+		return nil, err
+	}
+
+	setCompetitionStatus(&c)
+	return &c, nil
+}
+
 // Hämtar alla tävlingar
 func (r *CompetitionRepository) GetAllCompetitions() ([]models.Competition, error) {
-	rows, err := r.DB.Query(`
-		SELECT id, name, description, start_date, end_date, created_by_user_id, created_at
-		FROM competitions
-		ORDER BY created_at DESC`)
+	rows, err := r.DB.Query(`SELECT ` + competitionColumns + ` FROM competitions ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -23,39 +65,51 @@ func (r *CompetitionRepository) GetAllCompetitions() ([]models.Competition, erro
 
 	var competitions []models.Competition
 	for rows.Next() {
-		var c models.Competition
-		err := rows.Scan(
-			&c.ID,
-			&c.Name,
-			&c.Description,
-			&c.StartDate,
-			&c.EndDate,
-			&c.CreatedByUserID,
-			&c.CreatedAt,
-		)
+		c, err := scanCompetition(rows)
 		if err != nil {
 			return nil, err
 		}
+		competitions = append(competitions, *c)
+	}
 
-		// Räkna ut status (Active/Upcoming/Ended)
-		now := time.Now().UTC()
-		if now.Before(c.StartDate) {
-			c.Status = "upcoming"
-		} else if now.After(c.EndDate) {
-			c.Status = "ended"
-		} else {
-			c.Status = "active"
-		}
+	return competitions, rows.Err()
+}
 
-		competitions = append(competitions, c)
+// GetCompetitionsToFinalize hämtar tävlingar vars slutdatum har passerat men som ännu inte har arkiverats.
+func (r *CompetitionRepository) GetCompetitionsToFinalize() ([]models.Competition, error) {
+	rows, err := r.DB.Query(`SELECT ` + competitionColumns + ` FROM competitions WHERE end_date < NOW() AND finalized_at IS NULL ORDER BY end_date ASC`)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return competitions, nil
+	var competitions []models.Competition
+	for rows.Next() {
+		c, err := scanCompetition(rows)
+		if err != nil {
+			return nil, err
+		}
+		competitions = append(competitions, *c)
+	}
+
+	return competitions, rows.Err()
 }
 
 func (r *CompetitionRepository) CreateCompetition(c *models.Competition) (int64, error) {
-	query := `INSERT INTO competitions (name, description, start_date, end_date, created_by_user_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	err := r.DB.QueryRow(query, c.Name, c.Description, c.StartDate, c.EndDate, c.CreatedByUserID).Scan(&c.ID)
+	if c.CompetitionType == "" {
+		c.CompetitionType = models.CompetitionTypeIndividual
+	}
+	if c.PrizeTopN == 0 {
+		c.PrizeTopN = 3
+	}
+	rules, err := json.Marshal(c.Rules)
+	if err != nil {
+		return 0, err
+	}
+	query := `INSERT INTO competitions (name, description, start_date, end_date, created_by_user_id, competition_type, invite_only, prize_badge_id, prize_top_n, rules)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	err = r.DB.QueryRow(query, c.Name, c.Description, c.StartDate, c.EndDate, c.CreatedByUserID,
+		c.CompetitionType, c.InviteOnly, c.PrizeBadgeID, c.PrizeTopN, rules).Scan(&c.ID)
 	if err != nil {
 		return 0, err
 	}
@@ -63,17 +117,8 @@ func (r *CompetitionRepository) CreateCompetition(c *models.Competition) (int64,
 }
 
 func (r *CompetitionRepository) GetCompetitionByID(id int64) (*models.Competition, error) {
-	row := r.DB.QueryRow(`SELECT id, name, description, start_date, end_date, created_by_user_id, created_at FROM competitions WHERE id = $1`, id)
-	var comp models.Competition
-	err := row.Scan(
-		&comp.ID,
-		&comp.Name,
-		&comp.Description,
-		&comp.StartDate,
-		&comp.EndDate,
-		&comp.CreatedByUserID,
-		&comp.CreatedAt,
-	)
+	row := r.DB.QueryRow(`SELECT `+competitionColumns+` FROM competitions WHERE id = $1`, id)
+	comp, err := scanCompetition(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil //
@@ -81,12 +126,17 @@ func (r *CompetitionRepository) GetCompetitionByID(id int64) (*models.Competitio
 		return nil, err
 
 	}
-	return &comp, nil
+	return comp, nil
 }
 
 func (r *CompetitionRepository) UpdateCompetition(c *models.Competition) error {
-	_, err := r.DB.Exec(`UPDATE competitions SET name = $1, description = $2, start_date = $3, end_date = $4 WHERE id = $5`,
-		c.Name, c.Description, c.StartDate, c.EndDate, c.ID)
+	rules, err := json.Marshal(c.Rules)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.Exec(`UPDATE competitions SET name = $1, description = $2, start_date = $3, end_date = $4,
+		competition_type = $5, invite_only = $6, prize_badge_id = $7, prize_top_n = $8, rules = $9 WHERE id = $10`,
+		c.Name, c.Description, c.StartDate, c.EndDate, c.CompetitionType, c.InviteOnly, c.PrizeBadgeID, c.PrizeTopN, rules, c.ID)
 	return err
 }
 
@@ -94,3 +144,378 @@ func (r *CompetitionRepository) DeleteCompetition(id int64) error {
 	_, err := r.DB.Exec(`DELETE FROM competitions WHERE id = $1`, id)
 	return err
 }
+
+// setCompetitionStatus räknar ut status (Active/Upcoming/Ended) utifrån dagens datum.
+func setCompetitionStatus(c *models.Competition) {
+	now := time.Now().UTC()
+	if now.Before(c.StartDate) {
+		c.Status = "upcoming"
+	} else if now.After(c.EndDate) {
+		c.Status = "ended"
+	} else {
+		c.Status = "active"
+	}
+}
+
+// rulesCondition är SQL-villkoret för tävlingens regler. Parametrarna $4-$6 kommer från ruleArgs.
+// En tom lista betyder att allt räknas.
+const rulesCondition = `(COALESCE(cardinality($4::text[]), 0) = 0 OR a.activity_type = ANY($4))
+		  AND (COALESCE(cardinality($5::text[]), 0) = 0 OR a.space_key = ANY($5))
+		  AND (COALESCE(cardinality($6::text[]), 0) = 0 OR a.page_path && $6)`
+
+// leaderboardArgs returnerar parametrarna $1-$6 för leaderboard-frågorna.
+func leaderboardArgs(c *models.Competition) []interface{} {
+	return []interface{}{
+		c.StartDate,
+		c.EndDate,
+		c.ID,
+		pq.Array(c.Rules.ActivityTypes),
+		pq.Array(c.Rules.SpaceKeys),
+		pq.Array(c.Rules.AncestorPageIDs),
+	}
+}
+
+// participantCondition returnerar SQL-villkoret för vilka användares aktiviteter som räknas
+// i tävlingen. Villkoret förutsätter att aktiviteterna heter "a" och att tävlingens ID är $3.
+//   - Individuell tävling: alla användare utom de som har valt att lämna. Är tävlingen
+//     invite-only räknas bara de som har gått med.
+//   - Lagtävling: medlemmar i anmälda team, förutom de som själva har valt att lämna.
+func participantCondition(c *models.Competition) string {
+	if c.CompetitionType == models.CompetitionTypeTeam {
+		return `a.user_id IN (
+				SELECT ut.user_id FROM user_teams ut
+				JOIN competition_participants cp ON cp.team_id = ut.team_id
+				WHERE cp.competition_id = $3 AND cp.status = 'joined'
+			)
+			AND a.user_id NOT IN (
+				SELECT user_id FROM competition_participants
+				WHERE competition_id = $3 AND user_id IS NOT NULL AND status = 'left'
+			)`
+	}
+	if c.InviteOnly {
+		return `a.user_id IN (
+				SELECT user_id FROM competition_participants
+				WHERE competition_id = $3 AND user_id IS NOT NULL AND status = 'joined'
+			)`
+	}
+	return `a.user_id NOT IN (
+				SELECT user_id FROM competition_participants
+				WHERE competition_id = $3 AND user_id IS NOT NULL AND status = 'left'
+			)`
+}
+
+// leaderboardQuerier täcker både *sql.DB och *sql.Tx, så att slutresultatet kan räknas i
+// samma transaktion som arkiveringen.
+type leaderboardQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// GetCompetitionLeaderboard räknar ihop deltagarnas poäng från alla aktiviteter inom tävlingens
+// tidsfönster och rankar användarna (och valfritt teamen) efter totalpoäng.
+// I en lagtävling rankas alltid teamen.
+func (r *CompetitionRepository) GetCompetitionLeaderboard(c *models.Competition, includeTeams bool) (*models.CompetitionLeaderboard, error) {
+	return competitionLeaderboard(r.DB, c, includeTeams)
+}
+
+func competitionLeaderboard(q leaderboardQuerier, c *models.Competition, includeTeams bool) (*models.CompetitionLeaderboard, error) {
+	leaderboard := &models.CompetitionLeaderboard{
+		CompetitionID:   c.ID,
+		CompetitionType: c.CompetitionType,
+		StartDate:       c.StartDate,
+		EndDate:         c.EndDate,
+		Users:           []models.CompetitionLeaderboardEntry{},
+	}
+
+	rows, err := q.Query(`
+		SELECT u.id, u.display_name, u.avatar_url, a.activity_type, SUM(a.points_awarded)
+		FROM activities a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.occurred_at >= $1 AND a.occurred_at <= $2
+		  AND `+participantCondition(c)+`
+		  AND `+rulesCondition+`
+		GROUP BY u.id, u.display_name, u.avatar_url, a.activity_type`,
+		leaderboardArgs(c)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIndex := make(map[int64]int)
+	for rows.Next() {
+		var (
+			entry        models.CompetitionLeaderboardEntry
+			activityType string
+			points       int
+		)
+		if err := rows.Scan(&entry.UserID, &entry.DisplayName, &entry.AvatarURL, &activityType, &points); err != nil {
+			return nil, err
+		}
+		points = applyMultiplier(c.Rules, activityType, points)
+
+		i, found := userIndex[entry.UserID]
+		if !found {
+			entry.Breakdown = make(map[string]int)
+			leaderboard.Users = append(leaderboard.Users, entry)
+			i = len(leaderboard.Users) - 1
+			userIndex[entry.UserID] = i
+		}
+		leaderboard.Users[i].Breakdown[activityType] += points
+		leaderboard.Users[i].TotalPoints += points
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(leaderboard.Users, func(i, j int) bool {
+		a, b := leaderboard.Users[i], leaderboard.Users[j]
+		if a.TotalPoints != b.TotalPoints {
+			return a.TotalPoints > b.TotalPoints
+		}
+		return a.DisplayName < b.DisplayName
+	})
+	// Lika poäng ger samma placering och nästa placering hoppas över (1, 1, 3).
+	for i := range leaderboard.Users {
+		if i > 0 && leaderboard.Users[i].TotalPoints == leaderboard.Users[i-1].TotalPoints {
+			leaderboard.Users[i].Rank = leaderboard.Users[i-1].Rank
+		} else {
+			leaderboard.Users[i].Rank = i + 1
+		}
+	}
+
+	if includeTeams || c.CompetitionType == models.CompetitionTypeTeam {
+		teams, err := competitionTeamLeaderboard(q, c)
+		if err != nil {
+			return nil, err
+		}
+		leaderboard.Teams = teams
+	}
+
+	return leaderboard, nil
+}
+
+// competitionTeamLeaderboard summerar de deltagande teammedlemmarnas poäng inom
+// tävlingens tidsfönster. I en lagtävling räknas bara de team som är anmälda.
+func competitionTeamLeaderboard(q leaderboardQuerier, c *models.Competition) ([]models.CompetitionTeamLeaderboardEntry, error) {
+	teamCondition := "TRUE"
+	if c.CompetitionType == models.CompetitionTypeTeam {
+		teamCondition = `t.id IN (
+				SELECT team_id FROM competition_participants
+				WHERE competition_id = $3 AND team_id IS NOT NULL AND status = 'joined'
+			)`
+	}
+
+	rows, err := q.Query(`
+		SELECT t.id, t.name, a.activity_type, SUM(a.points_awarded)
+		FROM activities a
+		JOIN user_teams ut ON ut.user_id = a.user_id
+		JOIN teams t ON t.id = ut.team_id
+		WHERE a.occurred_at >= $1 AND a.occurred_at <= $2
+		  AND `+participantCondition(c)+`
+		  AND `+rulesCondition+`
+		  AND `+teamCondition+`
+		GROUP BY t.id, t.name, a.activity_type`,
+		leaderboardArgs(c)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []models.CompetitionTeamLeaderboardEntry{}
+	teamIndex := make(map[int64]int)
+	for rows.Next() {
+		var (
+			entry        models.CompetitionTeamLeaderboardEntry
+			activityType string
+			points       int
+		)
+		if err := rows.Scan(&entry.TeamID, &entry.TeamName, &activityType, &points); err != nil {
+			return nil, err
+		}
+		points = applyMultiplier(c.Rules, activityType, points)
+
+		i, found := teamIndex[entry.TeamID]
+		if !found {
+			entry.Breakdown = make(map[string]int)
+			teams = append(teams, entry)
+			i = len(teams) - 1
+			teamIndex[entry.TeamID] = i
+		}
+		teams[i].Breakdown[activityType] += points
+		teams[i].TotalPoints += points
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(teams, func(i, j int) bool {
+		if teams[i].TotalPoints != teams[j].TotalPoints {
+			return teams[i].TotalPoints > teams[j].TotalPoints
+		}
+		return teams[i].TeamName < teams[j].TeamName
+	})
+	for i := range teams {
+		if i > 0 && teams[i].TotalPoints == teams[i-1].TotalPoints {
+			teams[i].Rank = teams[i-1].Rank
+		} else {
+			teams[i].Rank = i + 1
+		}
+	}
+
+	return teams, nil
+}
+
+// applyMultiplier räknar om summan för en aktivitetstyp med tävlingens multiplikator.
+func applyMultiplier(rules models.CompetitionRules, activityType string, points int) int {
+	return int(math.Round(float64(points) * rules.Multiplier(activityType)))
+}
+
+// FinalizeCompetition fryser slutresultatet i competition_results, delar ut prisbadgen till
+// de bästa och markerar tävlingen som avslutad. Allt sker i en transaktion så att
+// en tävling aldrig arkiveras två gånger. Returnerar false om tävlingen redan var arkiverad.
+func (r *CompetitionRepository) FinalizeCompetition(c *models.Competition) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Lås raden så att två samtidiga körningar inte arkiverar samma tävling
+	var finalizedAt sql.NullTime
+	if err := tx.QueryRow(`SELECT finalized_at FROM competitions WHERE id = $1 FOR UPDATE`, c.ID).Scan(&finalizedAt); err != nil {
+		return false, err
+	}
+	if finalizedAt.Valid {
+		return false, nil
+	}
+
+	// Resultatet räknas först när raden är låst, annars kan en annan körning hinna arkivera
+	// eller aktiviteter ändras mellan uträkningen och arkiveringen
+	leaderboard, err := competitionLeaderboard(tx, c, true)
+	if err != nil {
+		return false, err
+	}
+
+	// I en individuell tävling får de bästa användarna priset, i en lagtävling de bästa teamen.
+	prizeForUsers := c.PrizeBadgeID != nil && c.CompetitionType != models.CompetitionTypeTeam
+	prizeForTeams := c.PrizeBadgeID != nil && c.CompetitionType == models.CompetitionTypeTeam
+
+	insertResult := `
+		INSERT INTO competition_results (competition_id, user_id, team_id, name, rank, total_points, breakdown, prize_awarded)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	var winningUserIDs, winningTeamIDs []int64
+	for _, entry := range leaderboard.Users {
+		breakdown, err := json.Marshal(entry.Breakdown)
+		if err != nil {
+			return false, err
+		}
+		prize := prizeForUsers && entry.Rank <= c.PrizeTopN
+		if prize {
+			winningUserIDs = append(winningUserIDs, entry.UserID)
+		}
+		if _, err := tx.Exec(insertResult, c.ID, entry.UserID, nil, entry.DisplayName, entry.Rank, entry.TotalPoints, breakdown, prize); err != nil {
+			return false, err
+		}
+	}
+	for _, entry := range leaderboard.Teams {
+		breakdown, err := json.Marshal(entry.Breakdown)
+		if err != nil {
+			return false, err
+		}
+		prize := prizeForTeams && entry.Rank <= c.PrizeTopN
+		if prize {
+			winningTeamIDs = append(winningTeamIDs, entry.TeamID)
+		}
+		if _, err := tx.Exec(insertResult, c.ID, nil, entry.TeamID, entry.TeamName, entry.Rank, entry.TotalPoints, breakdown, prize); err != nil {
+			return false, err
+		}
+	}
+
+	// Vinnande team: alla medlemmar som inte själva har lämnat tävlingen får badgen
+	if len(winningTeamIDs) > 0 {
+		rows, err := tx.Query(`
+			SELECT DISTINCT ut.user_id
+			FROM user_teams ut
+			WHERE ut.team_id = ANY($1)
+			  AND ut.user_id NOT IN (
+				SELECT user_id FROM competition_participants
+				WHERE competition_id = $2 AND user_id IS NOT NULL AND status = 'left'
+			  )`, pq.Array(winningTeamIDs), c.ID)
+		if err != nil {
+			return false, err
+		}
+		for rows.Next() {
+			var userID int64
+			if err := rows.Scan(&userID); err != nil {
+				rows.Close()
+				return false, err
+			}
+			winningUserIDs = append(winningUserIDs, userID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, err
+		}
+	}
+
+	// Progress sätts till badgens criteria_value så att den räknas som upplåst
+	awardPrize := `
+		INSERT INTO user_badges (user_id, badge_id, awarded_at, progress)
+		SELECT $1, id, NOW(), criteria_value FROM badges WHERE id = $2
+		ON CONFLICT (user_id, badge_id) DO UPDATE SET awarded_at = NOW(), progress = EXCLUDED.progress`
+	for _, userID := range winningUserIDs {
+		if _, err := tx.Exec(awardPrize, userID, *c.PrizeBadgeID); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE competitions SET finalized_at = NOW() WHERE id = $1`, c.ID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// GetCompetitionResults hämtar det arkiverade slutresultatet. Returnerar nil om tävlingen
+// ännu inte är avslutad och arkiverad.
+func (r *CompetitionRepository) GetCompetitionResults(c *models.Competition) (*models.CompetitionResults, error) {
+	if c.FinalizedAt == nil {
+		return nil, nil
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT user_id, team_id, name, rank, total_points, breakdown, prize_awarded
+		FROM competition_results
+		WHERE competition_id = $1
+		ORDER BY rank ASC, name ASC`, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := &models.CompetitionResults{
+		CompetitionID: c.ID,
+		FinalizedAt:   *c.FinalizedAt,
+		Users:         []models.CompetitionResult{},
+	}
+	for rows.Next() {
+		var (
+			result    models.CompetitionResult
+			breakdown []byte
+		)
+		if err := rows.Scan(&result.UserID, &result.TeamID, &result.Name, &result.Rank, &result.TotalPoints, &breakdown, &result.PrizeAwarded); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(breakdown, &result.Breakdown); err != nil {
+			return nil, err
+		}
+
+		if result.TeamID != nil {
+			results.Teams = append(results.Teams, result)
+		} else {
+			results.Users = append(results.Users, result)
+		}
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"gamification-api/backend/models"
+)
+
+// IdentityRepository kopplar ihop användar-ID:n i olika aktivitetskällor med våra användare.
+type IdentityRepository struct {
+	DB *sql.DB
+}
+
+// GetUserIDByIdentity hämtar användaren som är kopplad till ett ID i en källa, eller 0 om ingen är det.
+func (r *IdentityRepository) GetUserIDByIdentity(source, externalID string) (int64, error) {
+	var userID int64
+	err := r.DB.QueryRow(`SELECT user_id FROM user_identities WHERE source = $1 AND external_id = $2`, source, externalID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return userID, err
+}
+
+// LinkIdentity kopplar ett ID i en källa till en användare. En befintlig koppling flyttas.
+func (r *IdentityRepository) LinkIdentity(source, externalID string, userID int64) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO user_identities (source, external_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, external_id) DO UPDATE SET user_id = EXCLUDED.user_id`,
+		source, externalID, userID)
+	return err
+}
+
+// GetIdentitiesByUserID hämtar alla ID:n i andra källor som är kopplade till en användare.
+func (r *IdentityRepository) GetIdentitiesByUserID(userID int64) ([]models.UserIdentity, error) {
+	rows, err := r.DB.Query(`
+		SELECT source, external_id, user_id, created_at FROM user_identities
+		WHERE user_id = $1 ORDER BY source, external_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var i models.UserIdentity
+		if err := rows.Scan(&i.Source, &i.ExternalID, &i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity tar bort en koppling från en användare.
+func (r *IdentityRepository) UnlinkIdentity(userID int64, source, externalID string) error {
+	_, err := r.DB.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND source = $2 AND external_id = $3`, userID, source, externalID)
+	return err
+}
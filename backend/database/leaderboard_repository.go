@@ -9,20 +9,25 @@ type LeaderBoardRepository struct {
 	DB *sql.DB
 }
 
-func (repo *LeaderBoardRepository) GetLeaderboardByDate(date string) ([]models.LeaderboardEntry, error) {
+// GetLeaderboardByDate hämtar dagens poäng per användare från poängliggaren, så att manuella
+// justeringar och återföringar syns. Om spaceKey inte är tom räknas bara poäng för aktiviteter
+// i det Confluence-spacet.
+func (repo *LeaderBoardRepository) GetLeaderboardByDate(date, spaceKey string) ([]models.LeaderboardEntry, error) {
 	// SQL: använd explicit typkastrering till date (Postgres)
 	const q = `
 		SELECT 
 			u.id AS user_id,
 			u.display_name,u.avatar_url,
-			COALESCE(SUM(a.points_awarded), 0) AS total_points
+			COALESCE(SUM(l.points), 0) AS total_points
 		FROM users u
-		LEFT JOIN activities a ON a.user_id = u.id AND DATE(a.created_at) = $1::date
+		LEFT JOIN points_ledger l ON l.user_id = u.id AND DATE(l.occurred_at) = $1::date
+			AND l.entry_type NOT IN ('season_reset', 'opening_balance')
+			AND ($2 = '' OR EXISTS (SELECT 1 FROM activities a WHERE a.id = l.activity_id AND a.space_key = $2))
 		GROUP BY u.id, u.display_name, u.avatar_url
 		ORDER BY total_points DESC;
 	`
 
-	rows, err := repo.DB.Query(q, date)
+	rows, err := repo.DB.Query(q, date, spaceKey)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"gamification-api/backend/models"
+	"time"
+)
+
+var (
+	// ErrEntryReversed returneras när en rad som redan är återförd återförs igen.
+	ErrEntryReversed = errors.New("raden är redan återförd")
+	// ErrEntryNotReversible returneras för rader som inte kan återföras, t.ex. återföringar.
+	ErrEntryNotReversible = errors.New("raden kan inte återföras")
+)
+
+// LedgerRepository hanterar poängliggaren. Alla poängförändringar ska gå via liggaren så att
+// users.total_points och lifetime_points alltid går att förklara.
+type LedgerRepository struct {
+	DB *sql.DB
+}
+
+// ledgerExecutor täcker både *sql.DB och *sql.Tx, så att rader kan skrivas i andra repositoryns transaktioner.
+type ledgerExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// addLedgerEntry skriver en rad och uppdaterar användarens summor. Körs i en transaktion
+// av anroparen om raden hör ihop med andra ändringar.
+func addLedgerEntry(db ledgerExecutor, e *models.LedgerEntry) error {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO points_ledger (user_id, entry_type, points, lifetime_points, activity_id, reverses_entry_id, reason, actor_user_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		e.UserID, e.EntryType, e.Points, e.LifetimePoints, e.ActivityID, e.ReversesEntryID, e.Reason, e.ActorUserID, e.OccurredAt,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`UPDATE users SET total_points = total_points + $1, lifetime_points = lifetime_points + $2 WHERE id = $3`,
+		e.Points, e.LifetimePoints, e.UserID)
+	return err
+}
+
+const ledgerColumns = `id, user_id, entry_type, points, lifetime_points, activity_id, reverses_entry_id, reason, actor_user_id, occurred_at, created_at`
+
+func scanLedgerEntry(row rowScanner) (*models.LedgerEntry, error) {
+	var e models.LedgerEntry
+	var activityID, reversesID, actorID sql.NullInt64
+	err := row.Scan(&e.ID, &e.UserID, &e.EntryType, &e.Points, &e.LifetimePoints, &activityID, &reversesID, &e.Reason, &actorID, &e.OccurredAt, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if activityID.Valid {
+		e.ActivityID = &activityID.Int64
+	}
+	if reversesID.Valid {
+		e.ReversesEntryID = &reversesID.Int64
+	}
+	if actorID.Valid {
+		e.ActorUserID = &actorID.Int64
+	}
+	return &e, nil
+}
+
+// AddEntry skriver en rad i liggaren och uppdaterar användarens summor.
+func (r *LedgerRepository) AddEntry(e *models.LedgerEntry) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addLedgerEntry(tx, e); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetEntriesByUserID hämtar en användares rader, nyast först.
+func (r *LedgerRepository) GetEntriesByUserID(userID int64) ([]models.LedgerEntry, error) {
+	rows, err := r.DB.Query(`SELECT `+ledgerColumns+` FROM points_ledger WHERE user_id = $1 ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// GetEntry hämtar en rad. Returnerar nil om den inte finns.
+func (r *LedgerRepository) GetEntry(id int64) (*models.LedgerEntry, error) {
+	e, err := scanLedgerEntry(r.DB.QueryRow(`SELECT `+ledgerColumns+` FROM points_ledger WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// ReverseEntry återför en rad med en ny rad med motsatta poäng. Hör raden till en aktivitet
+// dras poängen även av från aktiviteten, så att topplistor och tävlingar stämmer med liggaren.
+func (r *LedgerRepository) ReverseEntry(id int64, reason string, actorID *int64) (*models.LedgerEntry, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original, err := scanLedgerEntry(tx.QueryRow(`SELECT `+ledgerColumns+` FROM points_ledger WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	if original.EntryType != models.LedgerEntryAward && original.EntryType != models.LedgerEntryAdjustment {
+		return nil, ErrEntryNotReversible
+	}
+
+	var reversed bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM points_ledger WHERE reverses_entry_id = $1)`, id).Scan(&reversed); err != nil {
+		return nil, err
+	}
+	if reversed {
+		return nil, ErrEntryReversed
+	}
+
+	reversal := &models.LedgerEntry{
+		UserID:          original.UserID,
+		EntryType:       models.LedgerEntryReversal,
+		Points:          -original.Points,
+		LifetimePoints:  -original.LifetimePoints,
+		ActivityID:      original.ActivityID,
+		ReversesEntryID: &original.ID,
+		Reason:          reason,
+		ActorUserID:     actorID,
+		OccurredAt:      original.OccurredAt,
+	}
+	if err := addLedgerEntry(tx, reversal); err != nil {
+		return nil, err
+	}
+
+	if original.ActivityID != nil {
+		if _, err := tx.Exec(`UPDATE activities SET points_awarded = points_awarded - $1 WHERE id = $2`, original.Points, *original.ActivityID); err != nil {
+			return nil, err
+		}
+	}
+
+	return reversal, tx.Commit()
+}
+
+// RebuildTotals räknar om users.total_points och lifetime_points från liggaren.
+func (r *LedgerRepository) RebuildTotals() error {
+	_, err := r.DB.Exec(`
+		UPDATE users u
+		SET total_points = COALESCE(l.points, 0), lifetime_points = COALESCE(l.lifetime_points, 0)
+		FROM users u2
+		LEFT JOIN (
+		    SELECT user_id, SUM(points) AS points, SUM(lifetime_points) AS lifetime_points
+		    FROM points_ledger GROUP BY user_id
+		) l ON l.user_id = u2.id
+		WHERE u.id = u2.id
+		  AND (u.total_points IS DISTINCT FROM COALESCE(l.points, 0)
+		       OR u.lifetime_points IS DISTINCT FROM COALESCE(l.lifetime_points, 0))`)
+	return err
+}
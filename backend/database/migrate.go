@@ -78,6 +78,265 @@ const schemaSQL = `
         created_at TIMESTAMPTZ DEFAULT NOW()
     );
 
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS competition_type VARCHAR(20) NOT NULL DEFAULT 'individual';
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS invite_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+    CREATE TABLE IF NOT EXISTS competition_participants (
+        id SERIAL PRIMARY KEY,
+        competition_id INTEGER NOT NULL REFERENCES competitions(id) ON DELETE CASCADE,
+        user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+        team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+        status VARCHAR(20) NOT NULL DEFAULT 'joined',
+        joined_at TIMESTAMPTZ DEFAULT NOW(),
+        CHECK ((user_id IS NULL) <> (team_id IS NULL))
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_competition_participants_user ON competition_participants(competition_id, user_id) WHERE user_id IS NOT NULL;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_competition_participants_team ON competition_participants(competition_id, team_id) WHERE team_id IS NOT NULL;
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS prize_badge_id INTEGER REFERENCES badges(id) ON DELETE SET NULL;
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS prize_top_n INTEGER NOT NULL DEFAULT 3;
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS finalized_at TIMESTAMPTZ;
+
+    CREATE TABLE IF NOT EXISTS competition_results (
+        id SERIAL PRIMARY KEY,
+        competition_id INTEGER NOT NULL REFERENCES competitions(id) ON DELETE CASCADE,
+        user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+        name VARCHAR(255) NOT NULL,
+        rank INTEGER NOT NULL,
+        total_points INTEGER NOT NULL,
+        breakdown JSONB NOT NULL DEFAULT '{}',
+        prize_awarded BOOLEAN NOT NULL DEFAULT FALSE,
+        created_at TIMESTAMPTZ DEFAULT NOW()
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_competition_results_competition_id ON competition_results(competition_id);
+    ALTER TABLE competitions ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '{}';
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS space_key VARCHAR(255);
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS page_path TEXT[];
+
+    CREATE TABLE IF NOT EXISTS confluence_spaces (
+        space_key VARCHAR(255) PRIMARY KEY,
+        name VARCHAR(255),
+        enabled BOOLEAN NOT NULL DEFAULT TRUE,
+        created_at TIMESTAMPTZ DEFAULT NOW()
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_activities_space_key ON activities(space_key);
+    ALTER TABLE confluence_spaces ADD COLUMN IF NOT EXISTS sync_cursor TIMESTAMPTZ;
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMPTZ;
+    UPDATE activities SET occurred_at = created_at WHERE occurred_at IS NULL;
+    ALTER TABLE activities ALTER COLUMN occurred_at SET DEFAULT NOW();
+    CREATE INDEX IF NOT EXISTS idx_activities_occurred_at ON activities(occurred_at);
+
+    CREATE TABLE IF NOT EXISTS backfill_jobs (
+        id SERIAL PRIMARY KEY,
+        space_key VARCHAR(255) NOT NULL,
+        from_date TIMESTAMPTZ,
+        to_date TIMESTAMPTZ,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending',
+        pages_done INTEGER NOT NULL DEFAULT 0,
+        activities_created INTEGER NOT NULL DEFAULT 0,
+        error TEXT,
+        created_at TIMESTAMPTZ DEFAULT NOW(),
+        started_at TIMESTAMPTZ,
+        finished_at TIMESTAMPTZ
+    );
+
+    CREATE TABLE IF NOT EXISTS backfill_job_pages (
+        job_id INTEGER NOT NULL REFERENCES backfill_jobs(id) ON DELETE CASCADE,
+        page_id VARCHAR(255) NOT NULL,
+        activities_created INTEGER NOT NULL DEFAULT 0,
+        completed_at TIMESTAMPTZ DEFAULT NOW(),
+        PRIMARY KEY (job_id, page_id)
+    );
+
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'confluence';
+
+    CREATE TABLE IF NOT EXISTS user_identities (
+        source VARCHAR(50) NOT NULL,
+        external_id VARCHAR(255) NOT NULL,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        created_at TIMESTAMPTZ DEFAULT NOW(),
+        PRIMARY KEY (source, external_id)
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+    CREATE TABLE IF NOT EXISTS source_cursors (
+        source VARCHAR(50) NOT NULL,
+        cursor_key VARCHAR(255) NOT NULL,
+        cursor_value TEXT NOT NULL,
+        updated_at TIMESTAMPTZ DEFAULT NOW(),
+        PRIMARY KEY (source, cursor_key)
+    );
+    CREATE TABLE IF NOT EXISTS scoring_rules (
+        id SERIAL PRIMARY KEY,
+        activity_type VARCHAR(50) NOT NULL,
+        version INTEGER NOT NULL,
+        base_points INTEGER NOT NULL,
+        diff_thresholds INTEGER[] NOT NULL DEFAULT '{}',
+        max_points INTEGER,
+        active BOOLEAN NOT NULL DEFAULT TRUE,
+        created_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        created_at TIMESTAMPTZ DEFAULT NOW(),
+        UNIQUE (activity_type, version)
+    );
+
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_scoring_rules_active ON scoring_rules(activity_type) WHERE active;
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS scoring_rule_id INTEGER REFERENCES scoring_rules(id) ON DELETE SET NULL;
+    -- Vad som ändrades i en uppdatering (ord, rubriker, tabeller osv.), se scoring.AnalyzeStorage
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS diff_breakdown JSONB;
+    -- Hash av sidans synliga text efter ändringen, används för att känna igen återställningar
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS content_hash VARCHAR(40);
+
+    CREATE TABLE IF NOT EXISTS activity_flags (
+        id SERIAL PRIMARY KEY,
+        activity_id INTEGER NOT NULL UNIQUE REFERENCES activities(id) ON DELETE CASCADE,
+        reasons TEXT[] NOT NULL,
+        points_withheld INTEGER NOT NULL DEFAULT 0,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending',
+        reviewed_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        reviewed_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ DEFAULT NOW()
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_activity_flags_status ON activity_flags(status);
+    CREATE INDEX IF NOT EXISTS idx_activities_content_hash ON activities(confluence_page_id, content_hash);
+
+    -- Tak och avtagande poäng per aktivitetstyp
+    ALTER TABLE scoring_rules ADD COLUMN IF NOT EXISTS daily_cap INTEGER;
+    ALTER TABLE scoring_rules ADD COLUMN IF NOT EXISTS weekly_cap INTEGER;
+    ALTER TABLE scoring_rules ADD COLUMN IF NOT EXISTS diminishing_factor DOUBLE PRECISION;
+
+    -- raw_points är poängen enligt regeln innan taken, points_awarded är vad användaren fick
+    ALTER TABLE activities ADD COLUMN IF NOT EXISTS raw_points INTEGER;
+    UPDATE activities SET raw_points = points_awarded WHERE raw_points IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_activities_user_occurred_at ON activities(user_id, occurred_at);
+
+    -- Poängliggaren. Varje poängförändring är en rad och raderna ändras aldrig, en rättelse
+    -- är en ny rad. users.total_points och lifetime_points är summan av raderna.
+    CREATE TABLE IF NOT EXISTS points_ledger (
+        id BIGSERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        entry_type VARCHAR(20) NOT NULL,
+        points INTEGER NOT NULL,
+        lifetime_points INTEGER NOT NULL,
+        activity_id INTEGER REFERENCES activities(id) ON DELETE SET NULL,
+        reverses_entry_id BIGINT UNIQUE REFERENCES points_ledger(id),
+        reason TEXT NOT NULL DEFAULT '',
+        actor_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        created_at TIMESTAMPTZ DEFAULT NOW()
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_points_ledger_user_id ON points_ledger(user_id);
+    CREATE INDEX IF NOT EXISTS idx_points_ledger_occurred_at ON points_ledger(occurred_at);
+    CREATE INDEX IF NOT EXISTS idx_points_ledger_activity_id ON points_ledger(activity_id);
+
+    CREATE TABLE IF NOT EXISTS seasons (
+        id SERIAL PRIMARY KEY,
+        name VARCHAR(255) NOT NULL,
+        start_date TIMESTAMPTZ NOT NULL,
+        end_date TIMESTAMPTZ NOT NULL,
+        auto_renew BOOLEAN NOT NULL DEFAULT FALSE,
+        archived_at TIMESTAMPTZ,
+        created_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        created_at TIMESTAMPTZ DEFAULT NOW(),
+        CHECK (end_date > start_date)
+    );
+
+    -- Slutställningen för en avslutad säsong
+    CREATE TABLE IF NOT EXISTS season_standings (
+        season_id INTEGER NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        display_name VARCHAR(255) NOT NULL,
+        points INTEGER NOT NULL,
+        rank INTEGER NOT NULL,
+        PRIMARY KEY (season_id, user_id)
+    );
+
+    -- Första gången: en rad per befintlig aktivitet och ett ingående saldo för resten av poängen
+    DO $$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM points_ledger) THEN
+            INSERT INTO points_ledger (user_id, entry_type, points, lifetime_points, activity_id, reason, occurred_at)
+            SELECT user_id, 'award', points_awarded, points_awarded, id, activity_type, occurred_at
+            FROM activities WHERE points_awarded <> 0;
+
+            INSERT INTO points_ledger (user_id, entry_type, points, lifetime_points, reason)
+            SELECT u.id, 'opening_balance',
+                   COALESCE(u.total_points, 0) - COALESCE(SUM(a.points_awarded), 0),
+                   COALESCE(u.lifetime_points, 0) - COALESCE(SUM(a.points_awarded), 0),
+                   'Saldo innan poängliggaren infördes'
+            FROM users u LEFT JOIN activities a ON a.user_id = u.id
+            GROUP BY u.id
+            HAVING COALESCE(u.total_points, 0) <> COALESCE(SUM(a.points_awarded), 0)
+                OR COALESCE(u.lifetime_points, 0) <> COALESCE(SUM(a.points_awarded), 0);
+        END IF;
+    END $$;
+
+    -- Sammansatta badge-villkor. Samtidigt betyder awarded_at IS NULL att badgen inte är upplåst,
+    -- tidigare skapades alla rader med awarded_at satt och progress >= criteria_value avgjorde.
+    DO $$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'badges' AND column_name = 'criteria') THEN
+            ALTER TABLE badges ADD COLUMN criteria JSONB;
+            ALTER TABLE user_badges ALTER COLUMN awarded_at DROP DEFAULT;
+            UPDATE user_badges ub SET awarded_at = NULL
+            FROM badges b
+            WHERE b.id = ub.badge_id AND ub.progress < b.criteria_value;
+        END IF;
+    END $$;
+
+    -- Badges i samma serie är nivåer på samma stege, t.ex. documenter 1-4
+    ALTER TABLE badges ADD COLUMN IF NOT EXISTS series VARCHAR(50) NOT NULL DEFAULT '';
+    ALTER TABLE badges ADD COLUMN IF NOT EXISTS tier INTEGER NOT NULL DEFAULT 0;
+
+    -- Revocable badges tas tillbaka vid omvärdering om villkoren inte längre uppfylls, andra behålls
+    ALTER TABLE badges ADD COLUMN IF NOT EXISTS revocable BOOLEAN NOT NULL DEFAULT FALSE;
+    -- Sätts när en badge behålls trots att villkoren inte längre uppfylls, så att det bara rapporteras en gång
+    ALTER TABLE user_badges ADD COLUMN IF NOT EXISTS grandfathered BOOLEAN NOT NULL DEFAULT FALSE;
+
+    CREATE TABLE IF NOT EXISTS badge_evaluation_runs (
+        id SERIAL PRIMARY KEY,
+        trigger VARCHAR(20) NOT NULL,
+        dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+        status VARCHAR(20) NOT NULL DEFAULT 'running',
+        awarded INTEGER NOT NULL DEFAULT 0,
+        revoked INTEGER NOT NULL DEFAULT 0,
+        grandfathered INTEGER NOT NULL DEFAULT 0,
+        changes JSONB NOT NULL DEFAULT '[]',
+        error TEXT,
+        triggered_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        finished_at TIMESTAMPTZ
+    );
+
+    -- Manuella badges delas ut av en admin med en motivering som visas på användarens badges
+    ALTER TABLE user_badges ADD COLUMN IF NOT EXISTS citation TEXT;
+    ALTER TABLE user_badges ADD COLUMN IF NOT EXISTS awarded_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+    CREATE TABLE IF NOT EXISTS badge_nominations (
+        id SERIAL PRIMARY KEY,
+        badge_id INTEGER NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+        nominee_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        nominated_by_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        citation TEXT NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'pending',
+        reviewed_by_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        reviewed_at TIMESTAMPTZ,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        CHECK (nominee_user_id <> nominated_by_user_id)
+    );
+    -- Samma person kan bara ha en nominering av samma kollega för samma badge som väntar
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_badge_nominations_pending
+        ON badge_nominations(badge_id, nominee_user_id, nominated_by_user_id) WHERE status = 'pending';
+
+    -- Ett jobb som körs uppdaterar heartbeat_at efter varje sida. Står det still har processen dött
+    -- och jobbet kan tas över, t.ex. av servern efter en avbruten körning från kommandoraden
+    ALTER TABLE backfill_jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ;
+
     CREATE INDEX IF NOT EXISTS idx_users_confluence_id ON users(confluence_author_id);
     CREATE INDEX IF NOT EXISTS idx_activities_user_id ON activities(user_id);
 `
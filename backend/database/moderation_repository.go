@@ -0,0 +1,239 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"gamification-api/backend/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrFlagReviewed returneras när en flagga som redan är granskad granskas igen.
+var ErrFlagReviewed = errors.New("flaggan är redan granskad")
+
+type ModerationRepository struct {
+	DB *sql.DB
+}
+
+const flagColumns = `f.id, f.activity_id, f.reasons, f.points_withheld, f.status, f.reviewed_by_user_id, f.reviewed_at, f.created_at,
+	a.user_id, u.display_name, a.activity_type, a.source, a.confluence_page_id, COALESCE(a.space_key, ''), a.occurred_at`
+
+const flagJoins = `FROM activity_flags f
+	JOIN activities a ON a.id = f.activity_id
+	JOIN users u ON u.id = a.user_id`
+
+func scanFlag(row rowScanner) (*models.ActivityFlag, error) {
+	var f models.ActivityFlag
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.ActivityID, pq.Array(&f.Reasons), &f.PointsWithheld, &f.Status, &reviewedBy, &reviewedAt, &f.CreatedAt,
+		&f.UserID, &f.DisplayName, &f.ActivityType, &f.Source, &f.ContentID, &f.SpaceKey, &f.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedBy.Valid {
+		f.ReviewedByUserID = &reviewedBy.Int64
+	}
+	if reviewedAt.Valid {
+		f.ReviewedAt = &reviewedAt.Time
+	}
+	return &f, nil
+}
+
+// GetFlags hämtar flaggor med en viss status, eller alla om status är tom. Äldst först, som en kö.
+func (r *ModerationRepository) GetFlags(status string) ([]models.ActivityFlag, error) {
+	rows, err := r.DB.Query(`SELECT `+flagColumns+` `+flagJoins+`
+		WHERE $1 = '' OR f.status = $1
+		ORDER BY f.created_at, f.id`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.ActivityFlag{}
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+// GetFlag hämtar en flagga. Returnerar nil om den inte finns.
+func (r *ModerationRepository) GetFlag(id int64) (*models.ActivityFlag, error) {
+	f, err := scanFlag(r.DB.QueryRow(`SELECT `+flagColumns+` `+flagJoins+` WHERE f.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return f, err
+}
+
+// CreateFlag flaggar en ny aktivitet vars poäng redan har hållits inne av Recorder.
+func (r *ModerationRepository) CreateFlag(activityID int64, reasons []string, pointsWithheld int) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO activity_flags (activity_id, reasons, points_withheld)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (activity_id) DO NOTHING`,
+		activityID, pq.Array(reasons), pointsWithheld)
+	return err
+}
+
+// WithholdActivity flaggar en aktivitet i efterhand. Poängen den gav dras av från användaren och
+// hålls inne tills flaggan är granskad. Är aktiviteten redan flaggad läggs bara skälet till.
+// Returnerar användaren som gjorde aktiviteten, så att användarens statistik kan räknas om.
+func (r *ModerationRepository) WithholdActivity(activityID int64, reason string) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var points int
+	var occurredAt time.Time
+	err = tx.QueryRow(`SELECT user_id, points_awarded, occurred_at FROM activities WHERE id = $1 FOR UPDATE`, activityID).Scan(&userID, &points, &occurredAt)
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted bool
+	err = tx.QueryRow(`
+		INSERT INTO activity_flags (activity_id, reasons, points_withheld)
+		VALUES ($1, ARRAY[$2::text], $3)
+		ON CONFLICT (activity_id) DO UPDATE SET
+		    reasons = CASE WHEN $2 = ANY(activity_flags.reasons) THEN activity_flags.reasons
+		                   ELSE array_append(activity_flags.reasons, $2) END
+		RETURNING (xmax = 0)`,
+		activityID, reason, points).Scan(&inserted)
+	if err != nil {
+		return 0, err
+	}
+
+	if inserted && points != 0 {
+		if _, err := tx.Exec(`UPDATE activities SET points_awarded = 0 WHERE id = $1`, activityID); err != nil {
+			return 0, err
+		}
+		err := addLedgerEntry(tx, &models.LedgerEntry{
+			UserID:         userID,
+			EntryType:      models.LedgerEntryReversal,
+			Points:         -points,
+			LifetimePoints: -points,
+			ActivityID:     &activityID,
+			Reason:         "Flaggad: " + reason,
+			OccurredAt:     occurredAt,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return userID, tx.Commit()
+}
+
+// ReviewFlag godkänner eller avvisar en flagga. Vid godkännande får användaren de innehållna poängen.
+func (r *ModerationRepository) ReviewFlag(id int64, approve bool, reviewerID int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var activityID int64
+	var points int
+	var status string
+	err = tx.QueryRow(`SELECT activity_id, points_withheld, status FROM activity_flags WHERE id = $1 FOR UPDATE`, id).Scan(&activityID, &points, &status)
+	if err != nil {
+		return err
+	}
+	if status != models.FlagStatusPending {
+		return ErrFlagReviewed
+	}
+
+	newStatus := models.FlagStatusRejected
+	if approve {
+		newStatus = models.FlagStatusApproved
+
+		var userID int64
+		var activityType string
+		var occurredAt time.Time
+		err := tx.QueryRow(`UPDATE activities SET points_awarded = $1 WHERE id = $2 RETURNING user_id, activity_type, occurred_at`,
+			points, activityID).Scan(&userID, &activityType, &occurredAt)
+		if err != nil {
+			return err
+		}
+		err = addLedgerEntry(tx, &models.LedgerEntry{
+			UserID:         userID,
+			EntryType:      models.LedgerEntryAward,
+			Points:         points,
+			LifetimePoints: points,
+			ActivityID:     &activityID,
+			Reason:         activityType + " (godkänd i moderering)",
+			ActorUserID:    &reviewerID,
+			OccurredAt:     occurredAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE activity_flags SET status = $1, reviewed_by_user_id = $2, reviewed_at = $3 WHERE id = $4`,
+		newStatus, reviewerID, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountActivitiesSince räknar en användares aktiviteter som skedde i intervallet [from, to].
+func (r *ModerationRepository) CountActivitiesSince(userID int64, from, to time.Time) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`
+		SELECT COUNT(*) FROM activities
+		WHERE user_id = $1 AND occurred_at >= $2 AND occurred_at <= $3`,
+		userID, from, to).Scan(&count)
+	return count, err
+}
+
+// FindRevertedEdits letar efter en tidigare version av innehållet med samma hash, som inte är den
+// närmast föregående. Finns en sådan returneras de mellanliggande ändringarna som userID gjorde,
+// eftersom den nya versionen tar bort just dem.
+func (r *ModerationRepository) FindRevertedEdits(contentID, hash string, version int, userID int64) (bool, []int64, error) {
+	var revertedTo int
+	err := r.DB.QueryRow(`
+		SELECT confluence_version_number FROM activities
+		WHERE confluence_page_id = $1 AND content_hash = $2 AND confluence_version_number < $3 - 1
+		ORDER BY confluence_version_number DESC
+		LIMIT 1`,
+		contentID, hash, version).Scan(&revertedTo)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	rows, err := r.DB.Query(`
+		SELECT id FROM activities
+		WHERE confluence_page_id = $1 AND user_id = $2
+		  AND confluence_version_number > $3 AND confluence_version_number < $4
+		  AND content_hash IS NOT NULL`,
+		contentID, userID, revertedTo, version)
+	if err != nil {
+		return true, nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return true, nil, err
+		}
+		ids = append(ids, id)
+	}
+	return true, ids, rows.Err()
+}
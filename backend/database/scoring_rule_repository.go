@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"gamification-api/backend/models"
+
+	"github.com/lib/pq"
+)
+
+// ScoringRuleRepository hanterar versionerade poängregler.
+type ScoringRuleRepository struct {
+	DB *sql.DB
+}
+
+const scoringRuleColumns = `id, activity_type, version, base_points, diff_thresholds, max_points,
+	daily_cap, weekly_cap, diminishing_factor, active, created_by_user_id, created_at`
+
+func scanScoringRule(row rowScanner) (*models.ScoringRule, error) {
+	var rule models.ScoringRule
+	var thresholds pq.Int64Array
+	var maxPoints, dailyCap, weeklyCap sql.NullInt64
+	var factor sql.NullFloat64
+	err := row.Scan(&rule.ID, &rule.ActivityType, &rule.Version, &rule.BasePoints, &thresholds, &maxPoints,
+		&dailyCap, &weeklyCap, &factor, &rule.Active, &rule.CreatedByUserID, &rule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.DiffThresholds = make([]int, len(thresholds))
+	for i, t := range thresholds {
+		rule.DiffThresholds[i] = int(t)
+	}
+	rule.MaxPoints = nullIntPtr(maxPoints)
+	rule.DailyCap = nullIntPtr(dailyCap)
+	rule.WeeklyCap = nullIntPtr(weeklyCap)
+	if factor.Valid {
+		rule.DiminishingFactor = &factor.Float64
+	}
+	return &rule, nil
+}
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// GetAllRules hämtar poängreglerna. Utan includeInactive returneras bara de aktiva.
+func (r *ScoringRuleRepository) GetAllRules(includeInactive bool) ([]models.ScoringRule, error) {
+	rows, err := r.DB.Query(`
+		SELECT `+scoringRuleColumns+` FROM scoring_rules
+		WHERE active OR $1
+		ORDER BY activity_type ASC, version DESC`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.ScoringRule{}
+	for rows.Next() {
+		rule, err := scanScoringRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// GetRuleByID hämtar en regelversion, eller nil om den inte finns.
+func (r *ScoringRuleRepository) GetRuleByID(id int64) (*models.ScoringRule, error) {
+	rule, err := scanScoringRule(r.DB.QueryRow(`SELECT `+scoringRuleColumns+` FROM scoring_rules WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// GetActiveRule hämtar den aktiva regeln för en aktivitetstyp, eller nil om ingen är aktiv.
+func (r *ScoringRuleRepository) GetActiveRule(activityType string) (*models.ScoringRule, error) {
+	rule, err := scanScoringRule(r.DB.QueryRow(`SELECT `+scoringRuleColumns+` FROM scoring_rules WHERE activity_type = $1 AND active`, activityType))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// CreateRuleVersion sparar en ny version av regeln för en aktivitetstyp och gör den aktiv.
+// Tidigare versioner behålls men avaktiveras.
+func (r *ScoringRuleRepository) CreateRuleVersion(rule *models.ScoringRule) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lås per aktivitetstyp så att två samtidiga ändringar inte får samma versionsnummer
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('scoring_rules:' || $1))`, rule.ActivityType); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE scoring_rules SET active = FALSE WHERE activity_type = $1 AND active`, rule.ActivityType); err != nil {
+		return err
+	}
+
+	thresholds := make(pq.Int64Array, len(rule.DiffThresholds))
+	for i, t := range rule.DiffThresholds {
+		thresholds[i] = int64(t)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO scoring_rules (activity_type, version, base_points, diff_thresholds, max_points,
+		                           daily_cap, weekly_cap, diminishing_factor, active, created_by_user_id)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6, $7, TRUE, $8
+		FROM scoring_rules WHERE activity_type = $1
+		RETURNING id, version, active, created_at`,
+		rule.ActivityType, rule.BasePoints, thresholds, rule.MaxPoints,
+		rule.DailyCap, rule.WeeklyCap, rule.DiminishingFactor, rule.CreatedByUserID,
+	).Scan(&rule.ID, &rule.Version, &rule.Active, &rule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ActivateRule gör en tidigare version aktiv igen, t.ex. för att backa en ändring.
+func (r *ScoringRuleRepository) ActivateRule(id int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE scoring_rules SET active = FALSE
+		WHERE active AND activity_type = (SELECT activity_type FROM scoring_rules WHERE id = $1)`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE scoring_rules SET active = TRUE WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,325 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gamification-api/backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSeasonOverlap returneras när en ny säsong överlappar en befintlig.
+	ErrSeasonOverlap = errors.New("säsongen överlappar en annan säsong")
+	// ErrSeasonArchived returneras när en arkiverad säsong ändras.
+	ErrSeasonArchived = errors.New("säsongen är redan arkiverad")
+)
+
+// SeasonRepository hanterar säsonger och deras arkiverade slutställningar.
+type SeasonRepository struct {
+	DB *sql.DB
+}
+
+const seasonColumns = `id, name, start_date, end_date, auto_renew, archived_at, created_by_user_id, created_at`
+
+// seasonPointsQuery räknar ihop poängen i liggaren under [$1, $2). Nollställningar och
+// ingående saldon är inte poäng som har tjänats in och räknas inte.
+const seasonPointsQuery = `
+	SELECT u.id, u.display_name, SUM(l.points)::int AS points,
+	       RANK() OVER (ORDER BY SUM(l.points) DESC)::int AS rank
+	FROM points_ledger l
+	JOIN users u ON u.id = l.user_id
+	WHERE l.occurred_at >= $1 AND l.occurred_at < $2
+	  AND l.entry_type NOT IN ('season_reset', 'opening_balance')
+	GROUP BY u.id, u.display_name
+	HAVING SUM(l.points) <> 0`
+
+func scanSeason(row rowScanner) (*models.Season, error) {
+	var s models.Season
+	var archivedAt sql.NullTime
+	var createdBy sql.NullInt64
+	err := row.Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate, &s.AutoRenew, &archivedAt, &createdBy, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if archivedAt.Valid {
+		s.ArchivedAt = &archivedAt.Time
+	}
+	if createdBy.Valid {
+		s.CreatedByUserID = &createdBy.Int64
+	}
+	s.Status = s.StatusAt(time.Now())
+	return &s, nil
+}
+
+func (r *SeasonRepository) querySeasons(query string, args ...interface{}) ([]models.Season, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []models.Season{}
+	for rows.Next() {
+		s, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *s)
+	}
+	return seasons, rows.Err()
+}
+
+// GetAllSeasons hämtar alla säsonger, senaste först.
+func (r *SeasonRepository) GetAllSeasons() ([]models.Season, error) {
+	return r.querySeasons(`SELECT ` + seasonColumns + ` FROM seasons ORDER BY start_date DESC`)
+}
+
+// GetSeason hämtar en säsong. Returnerar nil om den inte finns.
+func (r *SeasonRepository) GetSeason(id int64) (*models.Season, error) {
+	s, err := scanSeason(r.DB.QueryRow(`SELECT `+seasonColumns+` FROM seasons WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetCurrentSeason hämtar säsongen som pågår just nu. Returnerar nil om ingen gör det.
+func (r *SeasonRepository) GetCurrentSeason() (*models.Season, error) {
+	s, err := scanSeason(r.DB.QueryRow(`SELECT ` + seasonColumns + ` FROM seasons
+		WHERE start_date <= NOW() AND end_date > NOW() AND archived_at IS NULL
+		ORDER BY start_date
+		LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// CreateSeason skapar en säsong. Säsonger får inte överlappa varandra, annars skulle samma
+// poäng räknas i två säsonger och nollställningen hamna mitt i en annan säsong.
+func (r *SeasonRepository) CreateSeason(s *models.Season) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Låset hindrar att två överlappande säsonger skapas samtidigt
+	if _, err := tx.Exec(`LOCK TABLE seasons IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	if err := insertSeason(tx, s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSeason(tx *sql.Tx, s *models.Season) error {
+	var overlaps bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM seasons WHERE start_date < $2 AND end_date > $1)`,
+		s.StartDate, s.EndDate).Scan(&overlaps)
+	if err != nil {
+		return err
+	}
+	if overlaps {
+		return ErrSeasonOverlap
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO seasons (name, start_date, end_date, auto_renew, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		s.Name, s.StartDate, s.EndDate, s.AutoRenew, s.CreatedByUserID,
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return err
+	}
+	s.Status = s.StatusAt(time.Now())
+	return nil
+}
+
+// DeleteSeason tar bort en säsong som inte har arkiverats. Arkiverade säsonger är historik
+// och poängen har redan nollställts, så de går inte att ta bort.
+func (r *SeasonRepository) DeleteSeason(id int64) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var archivedAt sql.NullTime
+	if err := tx.QueryRow(`SELECT archived_at FROM seasons WHERE id = $1 FOR UPDATE`, id).Scan(&archivedAt); err != nil {
+		return err
+	}
+	if archivedAt.Valid {
+		return ErrSeasonArchived
+	}
+
+	if _, err := tx.Exec(`DELETE FROM seasons WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetStandings hämtar ställningen för en säsong. För arkiverade säsonger är det den sparade
+// slutställningen, annars räknas den fram ur poängliggaren.
+func (r *SeasonRepository) GetStandings(s *models.Season) ([]models.SeasonStanding, error) {
+	var rows *sql.Rows
+	var err error
+	if s.ArchivedAt != nil {
+		rows, err = r.DB.Query(`
+			SELECT user_id, display_name, points, rank FROM season_standings
+			WHERE season_id = $1
+			ORDER BY rank, display_name`, s.ID)
+	} else {
+		rows, err = r.DB.Query(seasonPointsQuery+` ORDER BY rank, u.display_name`, s.StartDate, s.EndDate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	standings := []models.SeasonStanding{}
+	for rows.Next() {
+		var st models.SeasonStanding
+		if err := rows.Scan(&st.UserID, &st.DisplayName, &st.Points, &st.Rank); err != nil {
+			return nil, err
+		}
+		standings = append(standings, st)
+	}
+	return standings, rows.Err()
+}
+
+// GetSeasonsToArchive hämtar säsonger som har tagit slut men inte arkiverats, äldst först.
+func (r *SeasonRepository) GetSeasonsToArchive() ([]models.Season, error) {
+	return r.querySeasons(`SELECT ` + seasonColumns + ` FROM seasons
+		WHERE end_date <= NOW() AND archived_at IS NULL
+		ORDER BY end_date`)
+}
+
+// ArchiveSeason sparar slutställningen och nollställer total_points för alla användare.
+// Poäng som har tjänats in efter säsongens slut behålls, lifetime_points påverkas inte.
+// Har säsongen AutoRenew skapas nästa säsong, om det inte redan finns en som krockar.
+// Returnerar false om säsongen redan var arkiverad.
+func (r *SeasonRepository) ArchiveSeason(s *models.Season) (*models.Season, bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var archivedAt sql.NullTime
+	if err := tx.QueryRow(`SELECT archived_at FROM seasons WHERE id = $1 FOR UPDATE`, s.ID).Scan(&archivedAt); err != nil {
+		return nil, false, err
+	}
+	if archivedAt.Valid {
+		return nil, false, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO season_standings (season_id, user_id, display_name, points, rank)
+		SELECT $3::int, p.* FROM (`+seasonPointsQuery+`) p`,
+		s.StartDate, s.EndDate, s.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Användarna låses så att inga poäng hinner skrivas mellan uträkningen och nollställningen
+	type reset struct {
+		userID int64
+		points int
+	}
+	rows, err := tx.Query(`
+		SELECT u.id, u.total_points - COALESCE((
+			SELECT SUM(l.points) FROM points_ledger l
+			WHERE l.user_id = u.id AND l.occurred_at >= $1
+			  AND l.entry_type NOT IN ('season_reset', 'opening_balance')
+		), 0)
+		FROM users u
+		FOR UPDATE`, s.EndDate)
+	if err != nil {
+		return nil, false, err
+	}
+	var resets []reset
+	for rows.Next() {
+		var rs reset
+		if err := rows.Scan(&rs.userID, &rs.points); err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		if rs.points != 0 {
+			resets = append(resets, rs)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	for _, rs := range resets {
+		err := addLedgerEntry(tx, &models.LedgerEntry{
+			UserID:     rs.userID,
+			EntryType:  models.LedgerEntrySeasonReset,
+			Points:     -rs.points,
+			Reason:     fmt.Sprintf("Säsongen %q avslutades", s.Name),
+			OccurredAt: s.EndDate,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE seasons SET archived_at = $1 WHERE id = $2`, now, s.ID); err != nil {
+		return nil, false, err
+	}
+	s.ArchivedAt = &now
+	s.Status = models.SeasonStatusArchived
+
+	var next *models.Season
+	if s.AutoRenew {
+		start, end := nextSeasonPeriod(s.StartDate, s.EndDate)
+		next = &models.Season{
+			Name:            nextSeasonName(s.Name, start),
+			StartDate:       start,
+			EndDate:         end,
+			AutoRenew:       true,
+			CreatedByUserID: s.CreatedByUserID,
+		}
+		if err := insertSeason(tx, next); err == ErrSeasonOverlap {
+			// Nästa säsong är redan planerad av en admin
+			next = nil
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return next, true, nil
+}
+
+// nextSeasonPeriod är perioden direkt efter [start, end) med samma längd. Säsonger som är
+// ett jämnt antal månader, t.ex. ett kvartal, förlängs med månader så att de inte glider.
+func nextSeasonPeriod(start, end time.Time) (time.Time, time.Time) {
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if months > 0 && start.AddDate(0, months, 0).Equal(end) {
+		return end, end.AddDate(0, months, 0)
+	}
+	return end, end.Add(end.Sub(start))
+}
+
+// nextSeasonName räknar upp ett avslutande nummer i namnet ("Säsong 3" blir "Säsong 4"),
+// annars läggs startdatumet till.
+func nextSeasonName(name string, start time.Time) string {
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			return fmt.Sprintf("%s %d", name[:i], n+1)
+		}
+	}
+	return fmt.Sprintf("%s %s", name, start.Format("2006-01-02"))
+}
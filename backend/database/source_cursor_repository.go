@@ -0,0 +1,30 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// SourceCursorRepository sparar hur långt en aktivitetskälla har kommit, t.ex. senast lästa commit per Git-repo.
+type SourceCursorRepository struct {
+	DB *sql.DB
+}
+
+// GetCursor hämtar en källas cursor, eller en tom sträng om källan inte har synkats än.
+func (r *SourceCursorRepository) GetCursor(source, key string) (string, error) {
+	var cursor string
+	err := r.DB.QueryRow(`SELECT cursor_value FROM source_cursors WHERE source = $1 AND cursor_key = $2`, source, key).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return cursor, err
+}
+
+// SetCursor sparar en källas cursor.
+func (r *SourceCursorRepository) SetCursor(source, key, cursor string) error {
+	_, err := r.DB.Exec(`
+		INSERT INTO source_cursors (source, cursor_key, cursor_value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source, cursor_key) DO UPDATE SET cursor_value = EXCLUDED.cursor_value, updated_at = NOW()`,
+		source, key, cursor)
+	return err
+}
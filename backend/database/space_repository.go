@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"gamification-api/backend/models"
+	"time"
+)
+
+// SpaceRepository hanterar listan över Confluence-spaces som ska synkroniseras.
+type SpaceRepository struct {
+	DB *sql.DB
+}
+
+// GetAllSpaces hämtar alla konfigurerade spaces.
+func (r *SpaceRepository) GetAllSpaces() ([]models.ConfluenceSpace, error) {
+	rows, err := r.DB.Query(`SELECT space_key, COALESCE(name, ''), enabled, created_at, sync_cursor FROM confluence_spaces ORDER BY space_key ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spaces := []models.ConfluenceSpace{}
+	for rows.Next() {
+		var s models.ConfluenceSpace
+		if err := rows.Scan(&s.Key, &s.Name, &s.Enabled, &s.CreatedAt, &s.SyncCursor); err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, s)
+	}
+
+	return spaces, rows.Err()
+}
+
+// GetEnabledSpaces hämtar alla spaces som ska synkroniseras, inklusive deras cursor.
+func (r *SpaceRepository) GetEnabledSpaces() ([]models.ConfluenceSpace, error) {
+	rows, err := r.DB.Query(`SELECT space_key, COALESCE(name, ''), enabled, created_at, sync_cursor FROM confluence_spaces WHERE enabled ORDER BY space_key ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spaces []models.ConfluenceSpace
+	for rows.Next() {
+		var s models.ConfluenceSpace
+		if err := rows.Scan(&s.Key, &s.Name, &s.Enabled, &s.CreatedAt, &s.SyncCursor); err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, s)
+	}
+
+	return spaces, rows.Err()
+}
+
+// IsSpaceEnabled avgör om ett space finns och är aktiverat för synkronisering.
+func (r *SpaceRepository) IsSpaceEnabled(key string) (bool, error) {
+	var enabled bool
+	err := r.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM confluence_spaces WHERE space_key = $1 AND enabled)`, key).Scan(&enabled)
+	return enabled, err
+}
+
+// UpdateSyncCursor flyttar fram ett spaces cursor. Den flyttas aldrig bakåt.
+func (r *SpaceRepository) UpdateSyncCursor(key string, cursor time.Time) error {
+	_, err := r.DB.Exec(`
+		UPDATE confluence_spaces SET sync_cursor = $2
+		WHERE space_key = $1 AND (sync_cursor IS NULL OR sync_cursor < $2)`,
+		key, cursor)
+	return err
+}
+
+// EnsureSpaces lägger till spaces från konfigurationen. Befintliga spaces lämnas orörda,
+// så ett space som en admin har stängt av aktiveras inte igen vid omstart.
+func (r *SpaceRepository) EnsureSpaces(keys []string) error {
+	for _, key := range keys {
+		if !models.ValidSpaceKey(key) {
+			return fmt.Errorf("ogiltig space-nyckel %q", key)
+		}
+		if _, err := r.DB.Exec(`INSERT INTO confluence_spaces (space_key) VALUES ($1) ON CONFLICT (space_key) DO NOTHING`, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertSpace lägger till ett space eller uppdaterar namn och status på ett befintligt.
+func (r *SpaceRepository) UpsertSpace(s *models.ConfluenceSpace) error {
+	return r.DB.QueryRow(`
+		INSERT INTO confluence_spaces (space_key, name, enabled)
+		VALUES ($1, NULLIF($2, ''), $3)
+		ON CONFLICT (space_key) DO UPDATE SET name = EXCLUDED.name, enabled = EXCLUDED.enabled
+		RETURNING created_at, sync_cursor`,
+		s.Key, s.Name, s.Enabled,
+	).Scan(&s.CreatedAt, &s.SyncCursor)
+}
+
+// DeleteSpace tar bort ett space från synkroniseringen. Redan registrerade aktiviteter behålls.
+func (r *SpaceRepository) DeleteSpace(key string) error {
+	_, err := r.DB.Exec(`DELETE FROM confluence_spaces WHERE space_key = $1`, key)
+	return err
+}
@@ -131,6 +131,14 @@ func (r *UserTeamRepository) RemoveUserFromTeam(userID, teamID int64) error {
 	return err
 }
 
+// IsUserInTeam kollar om en user är medlem i ett visst team
+func (r *UserTeamRepository) IsUserInTeam(userID, teamID int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM user_teams WHERE user_id = $1 AND team_id = $2)`
+	err := r.DB.QueryRow(query, userID, teamID).Scan(&exists)
+	return exists, err
+}
+
 // Hämta alla team för en viss user
 func (r *UserTeamRepository) GetUserTeamsByUserID(userID int64) ([]models.UserTeam, error) {
 	query := `SELECT user_id, team_id, joined_at FROM user_teams WHERE user_id = $1 ORDER BY joined_at DESC`
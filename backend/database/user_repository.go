@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"gamification-api/backend/models"
-	"time"
 )
 
 const defaultAvatarURL = "/static/avatars/default_avatar.jpg"
@@ -124,7 +123,6 @@ func (repo *UserRepository) CreateUser(user *models.User) (int64, error) {
 	}
 	defer rows.Close()
 
-	now := time.Now().UTC()
 	var badgeIDs []int64
 
 	for rows.Next() {
@@ -138,16 +136,16 @@ func (repo *UserRepository) CreateUser(user *models.User) (int64, error) {
 		return 0, err // Rollback
 	}
 
-	// Definiera SQL-frågan för insert
+	// Definiera SQL-frågan för insert. awarded_at sätts först när badgen låses upp
 	userBadgeSQL := `
-		INSERT INTO user_badges (user_id, badge_id, awarded_at, progress)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO user_badges (user_id, badge_id, progress)
+		VALUES ($1, $2, $3)
 		ON CONFLICT (user_id, badge_id) DO NOTHING
 	`
 
 	// Loopa igenom alla badges och kör Exec direkt på transaktionen
 	for _, badgeID := range badgeIDs {
-		if _, err := tx.Exec(userBadgeSQL, newID, badgeID, now, 0); err != nil {
+		if _, err := tx.Exec(userBadgeSQL, newID, badgeID, 0); err != nil {
 			return 0, err // Rollback
 		}
 	}
@@ -180,83 +178,27 @@ func (repo *UserRepository) DeleteUser(id int64) error {
 	return err
 }
 
-func (repo *UserRepository) UpdateUserPoints(id int64, points int) error {
-	_, err := repo.DB.Exec("UPDATE users SET total_points = total_points + $1, lifetime_points = lifetime_points + $1 WHERE id = $2", points, id)
-	return err
-}
-
-func (repo *UserRepository) EmptyUsersPoints(id int64) error {
-	query := `UPDATE users SET total_points = 0 WHERE id = $1`
+// incrementStat räknar upp en räknare i user_stats. Badges utvärderas av badges.Engine efteråt.
+func (repo *UserStatsRepository) incrementStat(id int64, statColumn string) error {
+	query := fmt.Sprintf(`UPDATE user_stats SET %s = %s + 1 WHERE user_id = $1`, statColumn, statColumn)
 	_, err := repo.DB.Exec(query, id)
 	return err
 }
 
-
-// updateStatsAndBadges är en generell hjälpfunktion som hanterar all logik i en transaktion.
-func (repo *UserStatsRepository) updateStatsAndBadges(id int64, statColumn, criteriaType string) error {
-	// 1. Starta transaktion
-	tx, err := repo.DB.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback() // Rulla tillbaka om något går fel
-
-	var newCount int
-
-	// 2. Uppdatera user_stats OCH hämta det nya värdet
-	statQuery := fmt.Sprintf(`UPDATE user_stats SET %s = %s + 1 WHERE user_id = $1 RETURNING %s`, statColumn, statColumn, statColumn)
-	err = tx.QueryRow(statQuery, id).Scan(&newCount)
-	if err != nil {
-		return err
-	}
-
-	// 3. Uppdatera progress i user_badges baserat på det nya värdet
-	progressQuery := `
-		UPDATE user_badges
-		SET progress = $1
-		WHERE user_id = $2 AND badge_id IN (
-			SELECT id FROM badges WHERE criteria_type = $3
-		)
-	`
-	if _, err := tx.Exec(progressQuery, newCount, id, criteriaType); err != nil {
-		return err
-	}
-
-	// 4. Kolla om detta nya värde låste upp en badge (sätt awarded_at)
-	awardQuery := `
-		UPDATE user_badges
-		SET awarded_at = NOW()
-		WHERE user_id = $1
-		  AND awarded_at IS NULL 
-		  AND badge_id IN (
-			  SELECT id FROM badges WHERE criteria_type = $2
-		  )
-		  AND progress >= (
-			  SELECT criteria_value FROM badges WHERE badges.id = user_badges.badge_id
-		  )
-	`
-	if _, err := tx.Exec(awardQuery, id, criteriaType); err != nil {
-		return err
-	}
-
-	// 5. Allt klart, committa
-	return tx.Commit()
-}
-
 func (repo *UserStatsRepository) UpdateUserStatsComments(id int64) error {
-	return repo.updateStatsAndBadges(id, "total_comments", "total_comments")
+	return repo.incrementStat(id, "total_comments")
 }
 
 func (repo *UserStatsRepository) UpdateUserStatsEditedPages(id int64) error {
-	return repo.updateStatsAndBadges(id, "total_edits_made", "total_edits_made")
+	return repo.incrementStat(id, "total_edits_made")
 }
 
 func (repo *UserStatsRepository) UpdateUserStatsCreatedPages(id int64) error {
-	return repo.updateStatsAndBadges(id, "total_created_pages", "total_created_pages")
+	return repo.incrementStat(id, "total_created_pages")
 }
 
 func (repo *UserStatsRepository) UpdateUserStatsResolvedComments(id int64) error {
-	return repo.updateStatsAndBadges(id, "total_resolved_comments", "total_resolved_comments")
+	return repo.incrementStat(id, "total_resolved_comments")
 }
 
 func (repo *UserStatsRepository) CreateStatsForUser(userID int64) error {
@@ -269,6 +211,50 @@ func (repo *UserStatsRepository) CreateStatsForUser(userID int64) error {
 	return err
 }
 
+// RebuildUserStats räknar om user_stats för alla användare utifrån activities. Används efter en
+// backfill, där aktiviteter kan ha tillkommit i efterhand. Badges utvärderas av badges.Engine efteråt.
+func (repo *UserStatsRepository) RebuildUserStats() error {
+	return repo.rebuildStats(nil)
+}
+
+// RebuildStatsForUser räknar om user_stats för en användare, t.ex. när en av användarens
+// aktiviteter har flaggats eller godkänts i modereringen.
+func (repo *UserStatsRepository) RebuildStatsForUser(userID int64) error {
+	return repo.rebuildStats(&userID)
+}
+
+// rebuildStats räknar om user_stats för userID, eller för alla användare om userID är nil.
+func (repo *UserStatsRepository) rebuildStats(userID *int64) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO user_stats (user_id, total_comments, total_edits_made, total_created_pages, total_resolved_comments)
+		SELECT u.id,
+		       COUNT(a.id) FILTER (WHERE a.activity_type = 'COMMENT_CREATED'),
+		       COUNT(a.id) FILTER (WHERE a.activity_type IN ('PAGE_UPDATED', 'DOC_UPDATED')),
+		       COUNT(a.id) FILTER (WHERE a.activity_type IN ('PAGE_CREATED', 'DOC_CREATED')),
+		       COUNT(a.id) FILTER (WHERE a.activity_type = 'RESOLVED_COMMENT')
+		FROM users u
+		LEFT JOIN activities a ON a.user_id = u.id
+		    -- Flaggade aktiviteter räknas först när de har godkänts
+		    AND NOT EXISTS (SELECT 1 FROM activity_flags f WHERE f.activity_id = a.id AND f.status <> 'approved')
+		WHERE $1::bigint IS NULL OR u.id = $1
+		GROUP BY u.id
+		ON CONFLICT (user_id) DO UPDATE SET
+		    total_comments = EXCLUDED.total_comments,
+		    total_edits_made = EXCLUDED.total_edits_made,
+		    total_created_pages = EXCLUDED.total_created_pages,
+		    total_resolved_comments = EXCLUDED.total_resolved_comments`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (repo *UserStatsRepository) GetUserStatsByUserID(userID int64) (*models.UserStats, error) {
 	row := repo.DB.QueryRow("SELECT user_id, total_comments, total_edits_made, total_created_pages, total_resolved_comments FROM user_stats WHERE user_id = $1", userID)
 	var stats models.UserStats
@@ -285,6 +271,33 @@ func (repo *UserStatsRepository) GetUserStatsByUserID(userID int64) (*models.Use
 	return &stats, nil
 }
 
+// GetUserStatsByUserIDAndSpace räknar fram statistiken från aktiviteterna i ett visst space.
+// user_stats innehåller bara totalen över alla spaces.
+func (repo *UserStatsRepository) GetUserStatsByUserIDAndSpace(userID int64, spaceKey string) (*models.UserStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE activity_type = 'COMMENT_CREATED'),
+			COUNT(*) FILTER (WHERE activity_type IN ('PAGE_UPDATED', 'DOC_UPDATED')),
+			COUNT(*) FILTER (WHERE activity_type IN ('PAGE_CREATED', 'DOC_CREATED')),
+			COUNT(*) FILTER (WHERE activity_type = 'RESOLVED_COMMENT')
+		FROM activities a
+		WHERE a.user_id = $1 AND a.space_key = $2
+		  -- Flaggade aktiviteter räknas först när de har godkänts, som i rebuildStats
+		  AND NOT EXISTS (SELECT 1 FROM activity_flags f WHERE f.activity_id = a.id AND f.status <> 'approved')
+	`
+	stats := models.UserStats{UserID: userID}
+	err := repo.DB.QueryRow(query, userID, spaceKey).Scan(
+		&stats.TotalComments,
+		&stats.TotalEdits,
+		&stats.TotalCreatedPages,
+		&stats.TotalResolvedComments,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // GetTopCommenter returnerar användaren med flest kommentarer.
 func (repo *UserStatsRepository) GetTopCommenter() (*models.UserTopStat, error) {
 	query := `
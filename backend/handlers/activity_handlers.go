@@ -65,16 +65,31 @@ func (h *ActivityHandler) CreateActivityHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	now := time.Now().UTC()
 	activity := &models.Activity{
 		UserID:                  requestBody.UserID,
 		ConfluencePageID:        requestBody.ConfluencePageID,
 		ConfluenceVersionNumber: requestBody.ConfluenceVersionNumber,
 		ActivityType:            requestBody.ActivityType,
 		PointsAwarded:           requestBody.PointsAwarded,
-		CreatedAt:               time.Now().UTC(),
+		OccurredAt:              now,
+		CreatedAt:               now,
 	}
 
-	id, err := h.Repo.CreateActivity(activity)
+	var entry *models.LedgerEntry
+	if activity.PointsAwarded != 0 {
+		entry = &models.LedgerEntry{
+			UserID:         activity.UserID,
+			EntryType:      models.LedgerEntryAward,
+			Points:         activity.PointsAwarded,
+			LifetimePoints: activity.PointsAwarded,
+			Reason:         activity.ActivityType + " (manuell)",
+			ActorUserID:    actorFromRequest(r),
+			OccurredAt:     activity.OccurredAt,
+		}
+	}
+
+	id, err := h.Repo.CreateActivity(activity, entry)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -117,7 +132,11 @@ func (h *ActivityHandler) UpdateActivityHandler(w http.ResponseWriter, r *http.R
 		PointsAwarded:           requestBody.PointsAwarded,
 	}
 
-	if err := h.Repo.UpdateActivity(activity); err != nil {
+	if err := h.Repo.UpdateActivity(activity, actorFromRequest(r)); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Activity not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -134,7 +153,7 @@ func (h *ActivityHandler) DeleteActivityHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if err := h.Repo.DeleteActivity(id); err != nil {
+	if err := h.Repo.DeleteActivity(id, actorFromRequest(r)); err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/database"
+	"gamification-api/backend/integrations/confluence"
+	"gamification-api/backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type BackfillHandler struct {
+	Repo       *database.BackfillRepository
+	Backfiller *confluence.Backfiller
+}
+
+// GetAllJobsHandler hanterar GET /backfill
+func (h *BackfillHandler) GetAllJobsHandler(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.Repo.GetAllJobs()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetJobHandler hanterar GET /backfill/{id}
+func (h *BackfillHandler) GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := h.getJobFromRequest(w, r)
+	if job == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// CreateJobHandler hanterar POST /backfill
+// Skapar ett jobb för ett space och datumintervall och startar det i bakgrunden.
+func (h *BackfillHandler) CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SpaceKey string `json:"spaceKey"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	job := &models.BackfillJob{SpaceKey: strings.TrimSpace(input.SpaceKey)}
+	var errs []FieldError
+	if job.SpaceKey == "" {
+		errs = append(errs, FieldError{"spaceKey", "spaceKey is required"})
+	}
+	from, err := models.ParseBackfillDate(input.From, false)
+	if err != nil {
+		errs = append(errs, FieldError{"from", "from must be a date (YYYY-MM-DD)"})
+	}
+	to, err := models.ParseBackfillDate(input.To, true)
+	if err != nil {
+		errs = append(errs, FieldError{"to", "to must be a date (YYYY-MM-DD)"})
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		errs = append(errs, FieldError{"to", "to must not be before from"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	job.From, job.To = from, to
+
+	if err := h.Repo.CreateJob(job); err != nil {
+		http.Error(w, "Could not create backfill job", http.StatusInternalServerError)
+		return
+	}
+	if err := h.Backfiller.Start(job.ID); err != nil {
+		http.Error(w, "Could not start backfill job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ResumeJobHandler hanterar POST /backfill/{id}/resume
+// Startar om ett avbrutet eller misslyckat jobb från dess senaste checkpoint.
+func (h *BackfillHandler) ResumeJobHandler(w http.ResponseWriter, r *http.Request) {
+	job := h.getJobFromRequest(w, r)
+	if job == nil {
+		return
+	}
+	if job.Status == models.BackfillStatusCompleted {
+		http.Error(w, "Backfill job is already completed", http.StatusConflict)
+		return
+	}
+
+	if err := h.Backfiller.Start(job.ID); err != nil {
+		if err == confluence.ErrBackfillRunning {
+			http.Error(w, "Backfill job is already running", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not start backfill job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *BackfillHandler) getJobFromRequest(w http.ResponseWriter, r *http.Request) *models.BackfillJob {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid backfill job ID", http.StatusBadRequest)
+		return nil
+	}
+
+	job, err := h.Repo.GetJob(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	if job == nil {
+		http.Error(w, "Backfill job not found", http.StatusNotFound)
+		return nil
+	}
+	return job
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/badges"
+	"gamification-api/backend/database"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type BadgeEvaluationHandler struct {
+	Repo        *database.BadgeEvaluationRepository
+	Reevaluator *badges.Reevaluator
+}
+
+// GetAllRunsHandler hanterar GET /badges/evaluations
+func (h *BadgeEvaluationHandler) GetAllRunsHandler(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.Repo.GetAllRuns()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetRunHandler hanterar GET /badges/evaluations/{id}
+// Svaret innehåller alla ändringar som körningen gjorde, eller skulle ha gjort vid dry run.
+func (h *BadgeEvaluationHandler) GetRunHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.Repo.GetRun(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Evaluation run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// CreateRunHandler hanterar POST /badges/evaluations
+// Startar en omvärdering av alla badges i bakgrunden. Bodyn är valfri.
+func (h *BadgeEvaluationHandler) CreateRunHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		DryRun bool `json:"dryRun"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.Reevaluator.Trigger(input.DryRun, actorFromRequest(r))
+	if err != nil {
+		if err == badges.ErrRunInProgress {
+			http.Error(w, "A badge evaluation is already running", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not start badge evaluation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
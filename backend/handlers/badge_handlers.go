@@ -3,10 +3,12 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"gamification-api/backend/badges"
 	"gamification-api/backend/database"
 	"gamification-api/backend/models"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -17,7 +19,12 @@ type BadgeHandler struct {
 }
 
 type UserBadgeHandler struct {
-	Repo *database.UserBadgeRepository
+	Repo   *database.UserBadgeRepository
+	Badges *badges.Engine
+	// Behövs för manuella badges och nomineringar
+	BadgeRepo      *database.BadgeRepository
+	UserRepo       *database.UserRepository
+	NominationRepo *database.BadgeNominationRepository
 }
 
 // GetAllBadgesHandler
@@ -62,6 +69,12 @@ func (h *BadgeHandler) CreateBadgeHandler(w http.ResponseWriter, r *http.Request
 		IconUrl       string `json:"iconUrl"`
 		CriteriaValue int    `json:"criteriaValue"`
 		CriteriaType  string `json:"criteriaType"`
+		// Criteria ersätter criteriaType och criteriaValue om det skickas med
+		Criteria *models.BadgeCriteria `json:"criteria"`
+		Series   string                `json:"series"`
+		Tier     int                   `json:"tier"`
+		// Revocable tar tillbaka badgen vid omvärdering om villkoren inte längre uppfylls
+		Revocable bool `json:"revocable"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
@@ -75,6 +88,12 @@ func (h *BadgeHandler) CreateBadgeHandler(w http.ResponseWriter, r *http.Request
 		IconUrl:       sql.NullString{String: requestBody.IconUrl, Valid: requestBody.IconUrl != ""},
 		CriteriaValue: requestBody.CriteriaValue,
 		CriteriaType:  requestBody.CriteriaType,
+		Series:        strings.TrimSpace(requestBody.Series),
+		Tier:          requestBody.Tier,
+		Revocable:     requestBody.Revocable,
+	}
+	if !applyCriteria(w, badge, requestBody.Criteria) {
+		return
 	}
 
 	newID, err := h.Repo.CreateBadge(badge)
@@ -104,6 +123,12 @@ func (h *BadgeHandler) UpdateBadgeHandler(w http.ResponseWriter, r *http.Request
 		IconUrl       string `json:"iconUrl"`
 		CriteriaValue int    `json:"criteriaValue"`
 		CriteriaType  string `json:"criteriaType"`
+		// Criteria ersätter criteriaType och criteriaValue om det skickas med
+		Criteria *models.BadgeCriteria `json:"criteria"`
+		Series   string                `json:"series"`
+		Tier     int                   `json:"tier"`
+		// Revocable tar tillbaka badgen vid omvärdering om villkoren inte längre uppfylls
+		Revocable bool `json:"revocable"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
@@ -118,6 +143,12 @@ func (h *BadgeHandler) UpdateBadgeHandler(w http.ResponseWriter, r *http.Request
 		IconUrl:       sql.NullString{String: requestBody.IconUrl, Valid: requestBody.IconUrl != ""},
 		CriteriaValue: requestBody.CriteriaValue,
 		CriteriaType:  requestBody.CriteriaType,
+		Series:        strings.TrimSpace(requestBody.Series),
+		Tier:          requestBody.Tier,
+		Revocable:     requestBody.Revocable,
+	}
+	if !applyCriteria(w, badge, requestBody.Criteria) {
+		return
 	}
 
 	if err := h.Repo.UpdateBadge(badge); err != nil {
@@ -128,6 +159,21 @@ func (h *BadgeHandler) UpdateBadgeHandler(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusOK)
 }
 
+// applyCriteria validerar villkoren och sätter criteria_type och criteria_value efter dem.
+// Vid fel skrivs svaret direkt och false returneras.
+func applyCriteria(w http.ResponseWriter, badge *models.Badge, criteria *models.BadgeCriteria) bool {
+	if criteria == nil {
+		return true
+	}
+	if err := badges.Validate(criteria); err != nil {
+		writeValidationErrors(w, []FieldError{{"criteria", err.Error()}})
+		return false
+	}
+	badge.Criteria = criteria
+	badge.CriteriaType, badge.CriteriaValue = badges.Summary(criteria)
+	return true
+}
+
 // DeleteBadgeHandler
 func (h *BadgeHandler) DeleteBadgeHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -181,6 +227,29 @@ func (h *UserBadgeHandler) GetUserBadgesByUserIDHandler(w http.ResponseWriter, r
 	json.NewEncoder(w).Encode(userBadges)
 }
 
+// GetUserBadgeProgressHandler hanterar GET /users/{id}/badges/progress
+// Visar progress mot alla badges, även de som användaren inte har låst upp än.
+func (h *UserBadgeHandler) GetUserBadgeProgressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.Badges.ProgressForUser(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if progress == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
 // CreateUserBadgeHandler
 func (h *UserBadgeHandler) CreateUserBadgeHandler(w http.ResponseWriter, r *http.Request) {
 	var requestBody struct {
@@ -195,6 +264,21 @@ func (h *UserBadgeHandler) CreateUserBadgeHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	// Manuella badges ska ha en motivering och delas ut via POST /users/{id}/badges
+	badge, err := h.BadgeRepo.GetBadgeByID(requestBody.BadgeID)
+	if err == sql.ErrNoRows {
+		writeValidationErrors(w, []FieldError{{"badgeId", "badge does not exist"}})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if badge.CriteriaType == models.BadgeCriteriaManual {
+		writeValidationErrors(w, []FieldError{{"badgeId", "manual badges are granted with POST /users/{id}/badges"}})
+		return
+	}
+
 	awardedAt := time.Now().UTC()
 	if requestBody.AwardedAt != nil {
 		awardedAt = *requestBody.AwardedAt
@@ -203,7 +287,7 @@ func (h *UserBadgeHandler) CreateUserBadgeHandler(w http.ResponseWriter, r *http
 	userBadge := &models.UserBadge{
 		UserID:    requestBody.UserID,
 		BadgeID:   requestBody.BadgeID,
-		AwardedAt: awardedAt,
+		AwardedAt: &awardedAt,
 		Progress:  requestBody.Progress,
 	}
 
@@ -269,7 +353,7 @@ func (h *UserBadgeHandler) UpdateUserBadgeHandler(w http.ResponseWriter, r *http
 	}
 
 	if requestBody.AwardedAt != nil {
-		existingUB.AwardedAt = *requestBody.AwardedAt
+		existingUB.AwardedAt = requestBody.AwardedAt
 	}
 	if requestBody.Progress != nil {
 		existingUB.Progress = *requestBody.Progress
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gamification-api/backend/contextkeys"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// maxCitationLength begränsar motiveringen, den visas på användarens badges.
+const maxCitationLength = 1000
+
+// GrantBadgeHandler hanterar POST /users/{id}/badges
+// En admin delar ut en manuell badge med en motivering.
+func (h *UserBadgeHandler) GrantBadgeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		BadgeID  int64  `json:"badgeId"`
+		Citation string `json:"citation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	citation := strings.TrimSpace(input.Citation)
+	if errs := validateCitation(citation); len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if !h.checkManualBadge(w, userID, input.BadgeID) {
+		return
+	}
+
+	grantedBy, _ := r.Context().Value(contextkeys.UserContextKey).(int64)
+	ub, err := h.Repo.GrantBadge(userID, input.BadgeID, citation, grantedBy)
+	if err != nil {
+		if err == database.ErrBadgeAlreadyAwarded {
+			http.Error(w, "User already has this badge", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not grant badge", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ub)
+}
+
+// GetNominationsHandler hanterar GET /nominations
+// Returnerar nomineringar som väntar på granskning om inget annat anges med ?status=, t.ex. ?status=all.
+func (h *UserBadgeHandler) GetNominationsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.NominationStatusPending
+	case "all":
+		status = ""
+	case models.NominationStatusPending, models.NominationStatusApproved, models.NominationStatusRejected:
+	default:
+		writeValidationErrors(w, []FieldError{{"status", "status must be pending, approved, rejected or all"}})
+		return
+	}
+
+	nominations, err := h.NominationRepo.GetNominations(status)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nominations)
+}
+
+// GetNominationHandler hanterar GET /nominations/{id}
+func (h *UserBadgeHandler) GetNominationHandler(w http.ResponseWriter, r *http.Request) {
+	n := h.getNominationFromRequest(w, r)
+	if n == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(n)
+}
+
+// CreateNominationHandler hanterar POST /nominations
+// En inloggad användare nominerar en kollega till en manuell badge.
+func (h *UserBadgeHandler) CreateNominationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		BadgeID  int64  `json:"badgeId"`
+		UserID   int64  `json:"userId"`
+		Citation string `json:"citation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	nominatorID, _ := r.Context().Value(contextkeys.UserContextKey).(int64)
+	n := &models.BadgeNomination{
+		BadgeID:           input.BadgeID,
+		NomineeUserID:     input.UserID,
+		NominatedByUserID: nominatorID,
+		Citation:          strings.TrimSpace(input.Citation),
+	}
+	errs := validateCitation(n.Citation)
+	if n.NomineeUserID == nominatorID {
+		errs = append(errs, FieldError{"userId", "you cannot nominate yourself"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	if !h.checkManualBadge(w, n.NomineeUserID, n.BadgeID) {
+		return
+	}
+
+	if err := h.NominationRepo.CreateNomination(n); err != nil {
+		if err == database.ErrNominationExists {
+			http.Error(w, "You have already nominated this user for this badge", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not create nomination", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.NominationRepo.GetNomination(n.ID)
+	if err != nil || created == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ApproveNominationHandler hanterar POST /nominations/{id}/approve
+// Badgen delas ut med nomineringens motivering. En admin kan skicka med en egen citation.
+func (h *UserBadgeHandler) ApproveNominationHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewNomination(w, r, true)
+}
+
+// RejectNominationHandler hanterar POST /nominations/{id}/reject
+func (h *UserBadgeHandler) RejectNominationHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewNomination(w, r, false)
+}
+
+func (h *UserBadgeHandler) reviewNomination(w http.ResponseWriter, r *http.Request, approve bool) {
+	n := h.getNominationFromRequest(w, r)
+	if n == nil {
+		return
+	}
+
+	var input struct {
+		Citation string `json:"citation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	citation := strings.TrimSpace(input.Citation)
+	if utf8.RuneCountInString(citation) > maxCitationLength {
+		writeValidationErrors(w, validateCitation(citation))
+		return
+	}
+
+	reviewerID, _ := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if err := h.NominationRepo.ReviewNomination(n.ID, approve, reviewerID, citation); err != nil {
+		switch err {
+		case database.ErrNominationReviewed:
+			http.Error(w, "Nomination is already reviewed", http.StatusConflict)
+		case database.ErrBadgeAlreadyAwarded:
+			http.Error(w, "User already has this badge", http.StatusConflict)
+		default:
+			http.Error(w, "Could not review nomination", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	n, err := h.NominationRepo.GetNomination(n.ID)
+	if err != nil || n == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(n)
+}
+
+// checkManualBadge kontrollerar att användaren finns och att badgen är manuell.
+// Vid fel skrivs svaret direkt och false returneras.
+func (h *UserBadgeHandler) checkManualBadge(w http.ResponseWriter, userID, badgeID int64) bool {
+	user, err := h.UserRepo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return false
+	}
+
+	badge, err := h.BadgeRepo.GetBadgeByID(badgeID)
+	if err == sql.ErrNoRows {
+		writeValidationErrors(w, []FieldError{{"badgeId", "badge does not exist"}})
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+	if badge.CriteriaType != models.BadgeCriteriaManual {
+		writeValidationErrors(w, []FieldError{{"badgeId", "only manual badges can be granted or nominated"}})
+		return false
+	}
+	return true
+}
+
+func validateCitation(citation string) []FieldError {
+	if citation == "" {
+		return []FieldError{{"citation", "citation is required"}}
+	}
+	if utf8.RuneCountInString(citation) > maxCitationLength {
+		return []FieldError{{"citation", "citation must be at most " + strconv.Itoa(maxCitationLength) + " characters"}}
+	}
+	return nil
+}
+
+func (h *UserBadgeHandler) getNominationFromRequest(w http.ResponseWriter, r *http.Request) *models.BadgeNomination {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid nomination ID", http.StatusBadRequest)
+		return nil
+	}
+
+	n, err := h.NominationRepo.GetNomination(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	if n == nil {
+		http.Error(w, "Nomination not found", http.StatusNotFound)
+		return nil
+	}
+	return n
+}
@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"gamification-api/backend/contextkeys"
 	"gamification-api/backend/database"
 	"gamification-api/backend/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
     "github.com/gorilla/mux"
 )
 
 type CompetitionHandler struct {
-	Repo *database.CompetitionRepository
+	Repo            *database.CompetitionRepository
+	UserRepo        *database.UserRepository
+	ParticipantRepo *database.CompetitionParticipantRepository
+	UserTeamRepo    *database.UserTeamRepository
+	BadgeRepo       *database.BadgeRepository
 }
 
 // GetAllActivitiesHandlers hanterar förfrågningar till /api/v1/competitions
@@ -29,30 +37,223 @@ func (h *CompetitionHandler) GetAllCompetitionsHandler(w http.ResponseWriter, r
 	// Omvandla er lista av användare till JSON och skicka den.
 	json.NewEncoder(w).Encode(competitions)
 }
+// competitionInput är body:n för att skapa eller uppdatera en tävling.
+// Fält som utelämnas lämnas orörda vid uppdatering.
+type competitionInput struct {
+	Name            *string                  `json:"name"`
+	Description     *string                  `json:"description"`
+	StartDate       *time.Time               `json:"startDate"`
+	EndDate         *time.Time               `json:"endDate"`
+	CompetitionType *string                  `json:"competitionType"`
+	InviteOnly      *bool                    `json:"inviteOnly"`
+	PrizeBadgeID    *int64                   `json:"prizeBadgeId"`
+	PrizeTopN       *int                     `json:"prizeTopN"`
+	Rules           *models.CompetitionRules `json:"rules"`
+}
+
+// apply skriver in de fält som skickades med till tävlingen.
+func (in competitionInput) apply(c *models.Competition) {
+	if in.Name != nil {
+		c.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Description != nil {
+		c.Description = sql.NullString{String: *in.Description, Valid: *in.Description != ""}
+	}
+	if in.StartDate != nil {
+		c.StartDate = in.StartDate.UTC()
+	}
+	if in.EndDate != nil {
+		c.EndDate = in.EndDate.UTC()
+	}
+	if in.CompetitionType != nil {
+		c.CompetitionType = *in.CompetitionType
+	}
+	if in.InviteOnly != nil {
+		c.InviteOnly = *in.InviteOnly
+	}
+	if in.PrizeBadgeID != nil {
+		c.PrizeBadgeID = in.PrizeBadgeID
+		if *in.PrizeBadgeID == 0 {
+			c.PrizeBadgeID = nil // 0 tar bort priset
+		}
+	}
+	if in.PrizeTopN != nil {
+		c.PrizeTopN = *in.PrizeTopN
+	}
+	if in.Rules != nil {
+		c.Rules = *in.Rules
+	}
+}
+
+// validateCompetition kontrollerar alla fält och returnerar ett fel per ogiltigt fält.
+// Prisbadgen slås upp i databasen, annars märks ett felaktigt ID först när tävlingen avslutas.
+func (h *CompetitionHandler) validateCompetition(c *models.Competition) ([]FieldError, error) {
+	var errs []FieldError
+
+	if c.Name == "" {
+		errs = append(errs, FieldError{"name", "name is required"})
+	} else if len(c.Name) > 255 {
+		errs = append(errs, FieldError{"name", "name must be at most 255 characters"})
+	}
+	if c.StartDate.IsZero() {
+		errs = append(errs, FieldError{"startDate", "startDate is required"})
+	}
+	if c.EndDate.IsZero() {
+		errs = append(errs, FieldError{"endDate", "endDate is required"})
+	}
+	if !c.StartDate.IsZero() && !c.EndDate.IsZero() && !c.EndDate.After(c.StartDate) {
+		errs = append(errs, FieldError{"endDate", "endDate must be after startDate"})
+	}
+	if c.CompetitionType != models.CompetitionTypeIndividual && c.CompetitionType != models.CompetitionTypeTeam {
+		errs = append(errs, FieldError{"competitionType", "competitionType must be 'individual' or 'team'"})
+	}
+	if c.PrizeBadgeID != nil {
+		if _, err := h.BadgeRepo.GetBadgeByID(*c.PrizeBadgeID); err == sql.ErrNoRows {
+			errs = append(errs, FieldError{"prizeBadgeId", "prizeBadgeId does not exist"})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.PrizeTopN < 1 {
+		errs = append(errs, FieldError{"prizeTopN", "prizeTopN must be at least 1"})
+	}
+	for _, activityType := range c.Rules.ActivityTypes {
+		if !isKnownActivityType(activityType) {
+			errs = append(errs, FieldError{"rules.activityTypes", "unknown activity type: " + activityType})
+		}
+	}
+	for activityType, multiplier := range c.Rules.Multipliers {
+		if !isKnownActivityType(activityType) {
+			errs = append(errs, FieldError{"rules.multipliers", "unknown activity type: " + activityType})
+		} else if multiplier < 0 {
+			errs = append(errs, FieldError{"rules.multipliers", "multiplier for " + activityType + " must not be negative"})
+		}
+	}
+
+	return errs, nil
+}
+
+func isKnownActivityType(activityType string) bool {
+	for _, t := range models.ActivityTypes {
+		if t == activityType {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateCompetitionHandler hanterar POST /competitions
+// Den inloggade användaren (från JWT) sparas som skapare av tävlingen.
 func (h *CompetitionHandler) CreateCompetitionHandler(w http.ResponseWriter, r *http.Request) {
-    var newComp models.Competition
-    
-    // Läs och avkoda JSON-datan från anropets body
-    err := json.NewDecoder(r.Body).Decode(&newComp)
-    if err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        return
-    }
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
 
-    // Skicka den nya tävlingen till repositoryt för att spara den
-    createdID, err := h.Repo.CreateCompetition(&newComp)
-    if err != nil {
-        http.Error(w, "Could not create competition", http.StatusInternalServerError)
-        return
-    }
-    
-    newComp.ID = createdID // Sätt det ID som databasen returnerade
+	// Läs och avkoda JSON-datan från anropets body
+	var input competitionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-    // Skicka tillbaka den nyskapade tävlingen som bekräftelse
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusCreated) // Status 201 Created
-    json.NewEncoder(w).Encode(newComp)
+	newComp := models.Competition{
+		CompetitionType: models.CompetitionTypeIndividual,
+		PrizeTopN:       3,
+	}
+	input.apply(&newComp)
+	newComp.CreatedByUserID = sql.NullInt64{Int64: userID, Valid: true}
+
+	errs, err := h.validateCompetition(&newComp)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	// Skicka den nya tävlingen till repositoryt för att spara den
+	createdID, err := h.Repo.CreateCompetition(&newComp)
+	if err != nil {
+		http.Error(w, "Could not create competition", http.StatusInternalServerError)
+		return
+	}
+
+	createdComp, err := h.Repo.GetCompetitionByID(createdID)
+	if err != nil || createdComp == nil {
+		http.Error(w, "Could not fetch created competition", http.StatusInternalServerError)
+		return
+	}
+
+	// Skicka tillbaka den nyskapade tävlingen som bekräftelse
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated) // Status 201 Created
+	json.NewEncoder(w).Encode(createdComp)
+}
+
+// UpdateCompetitionHandler hanterar PUT /competitions/{id}
+// Endast skaparen eller en admin får uppdatera. Datumen för en avslutad tävling kan inte ändras.
+func (h *CompetitionHandler) UpdateCompetitionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
+	existing, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.requireOwnerOrAdmin(w, existing, userID, "Only the creator or an admin can update this competition") {
+		return
+	}
+
+	var input competitionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated := *existing
+	input.apply(&updated)
+
+	errs, err := h.validateCompetition(&updated)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if existing.Status == "ended" {
+		if !updated.StartDate.Equal(existing.StartDate) {
+			errs = append(errs, FieldError{"startDate", "the dates of an ended competition cannot be changed"})
+		}
+		if !updated.EndDate.Equal(existing.EndDate) {
+			errs = append(errs, FieldError{"endDate", "the dates of an ended competition cannot be changed"})
+		}
+	}
+	if existing.Status != "upcoming" && updated.CompetitionType != existing.CompetitionType {
+		errs = append(errs, FieldError{"competitionType", "competitionType can only be changed before the competition starts"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if err := h.Repo.UpdateCompetition(&updated); err != nil {
+		http.Error(w, "Could not update competition", http.StatusInternalServerError)
+		return
+	}
+
+	updatedComp, err := h.Repo.GetCompetitionByID(updated.ID)
+	if err != nil || updatedComp == nil {
+		http.Error(w, "Could not fetch updated competition", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedComp)
 }
 
 // GetCompetitionByIDHandler hanterar GET /competitions/{id}
@@ -81,13 +282,20 @@ func (h *CompetitionHandler) GetCompetitionByIDHandler(w http.ResponseWriter, r
 }
 
 func (h * CompetitionHandler) DeleteCompetitionHandler(w http.ResponseWriter, r *http.Request) {
-    vars := mux.Vars(r) // Kräver att ni använder gorilla/mux
-    id, err := strconv.ParseInt(vars["id"], 10, 64)
-    if err != nil {
-        http.Error(w, "Invalid competition ID", http.StatusBadRequest)
+    userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+    if !ok {
+        http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+        return
+    }
+    competition, ok := h.getCompetitionFromRequest(w, r)
+    if !ok {
         return
     }
-    err = h.Repo.DeleteCompetition(id)
+    if !h.requireOwnerOrAdmin(w, competition, userID, "Only the creator or an admin can delete this competition") {
+        return
+    }
+
+    err := h.Repo.DeleteCompetition(competition.ID)
     if err != nil {
         http.Error(w, "Could not delete competition", http.StatusInternalServerError)
         return
@@ -95,3 +303,350 @@ func (h * CompetitionHandler) DeleteCompetitionHandler(w http.ResponseWriter, r
     w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
 
+// GetCompetitionLeaderboardHandler hanterar GET /competitions/{id}/leaderboard
+// Lägg till ?includeTeams=true för att även ranka teamen.
+func (h *CompetitionHandler) GetCompetitionLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid competition ID", http.StatusBadRequest)
+		return
+	}
+
+	competition, err := h.Repo.GetCompetitionByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if competition == nil {
+		http.Error(w, "Competition not found", http.StatusNotFound)
+		return
+	}
+
+	includeTeams := r.URL.Query().Get("includeTeams") == "true"
+	leaderboard, err := h.Repo.GetCompetitionLeaderboard(competition, includeTeams)
+	if err != nil {
+		http.Error(w, "Failed to fetch leaderboard: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaderboard)
+}
+
+// GetCompetitionResultsHandler hanterar GET /competitions/{id}/results
+// Resultatet är fryst när tävlingen avslutades och ändras inte om aktiviteter redigeras i efterhand.
+func (h *CompetitionHandler) GetCompetitionResultsHandler(w http.ResponseWriter, r *http.Request) {
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := h.Repo.GetCompetitionResults(competition)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		http.Error(w, "Competition has not been finalized yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// getCompetitionFromRequest läser {id} från URL:en och hämtar tävlingen.
+// Vid fel skrivs svaret direkt och ok blir false.
+func (h *CompetitionHandler) getCompetitionFromRequest(w http.ResponseWriter, r *http.Request) (competition *models.Competition, ok bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid competition ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	competition, err = h.Repo.GetCompetitionByID(id)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if competition == nil {
+		http.Error(w, "Competition not found", http.StatusNotFound)
+		return nil, false
+	}
+	return competition, true
+}
+
+// requireOwnerOrAdmin släpper igenom tävlingens skapare och admins.
+// Annars skrivs svaret direkt med meddelandet och false returneras.
+func (h *CompetitionHandler) requireOwnerOrAdmin(w http.ResponseWriter, competition *models.Competition, userID int64, message string) bool {
+	if competition.CreatedByUserID.Valid && competition.CreatedByUserID.Int64 == userID {
+		return true
+	}
+	user, err := h.UserRepo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+	if user == nil || !user.IsAdmin {
+		http.Error(w, message, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// GetParticipantsHandler hanterar GET /competitions/{id}/participants
+func (h *CompetitionHandler) GetParticipantsHandler(w http.ResponseWriter, r *http.Request) {
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	participants, err := h.ParticipantRepo.GetParticipants(competition.ID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(participants)
+}
+
+// AddParticipantHandler hanterar POST /competitions/{id}/participants
+// Anmäler ett team till en lagtävling, eller en användare till en individuell tävling.
+// I en tävling som kräver inbjudan blir användaren inbjuden och måste själv gå med.
+// Endast skaparen eller en admin får anmäla andra, själv går man med via /join.
+func (h *CompetitionHandler) AddParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !h.requireOwnerOrAdmin(w, competition, userID, "Only the creator or an admin can enroll participants") {
+		return
+	}
+
+	var input struct {
+		UserID int64 `json:"userId"`
+		TeamID int64 `json:"teamId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if (input.UserID > 0) == (input.TeamID > 0) {
+		http.Error(w, "Exactly one of userId and teamId must be set", http.StatusBadRequest)
+		return
+	}
+	if competition.Status == "ended" {
+		http.Error(w, "Competition has ended", http.StatusConflict)
+		return
+	}
+
+	var (
+		participant *models.CompetitionParticipant
+		err         error
+	)
+	if competition.CompetitionType == models.CompetitionTypeTeam {
+		if input.TeamID == 0 {
+			http.Error(w, "Users take part in team competitions through their team", http.StatusBadRequest)
+			return
+		}
+		// I en invite-only-tävling bjuds teamet in och en medlem tackar ja via join
+		status := models.ParticipantStatusJoined
+		if competition.InviteOnly {
+			status = models.ParticipantStatusInvited
+		}
+		if err := h.ParticipantRepo.SetTeamStatus(competition.ID, input.TeamID, status); err != nil {
+			http.Error(w, "Could not enroll team", http.StatusInternalServerError)
+			return
+		}
+		participant, err = h.ParticipantRepo.GetTeamParticipant(competition.ID, input.TeamID)
+	} else {
+		if input.UserID == 0 {
+			http.Error(w, "Teams can only be enrolled in team competitions", http.StatusBadRequest)
+			return
+		}
+		status := models.ParticipantStatusJoined
+		if competition.InviteOnly {
+			status = models.ParticipantStatusInvited
+		}
+		if err := h.ParticipantRepo.SetUserStatus(competition.ID, input.UserID, status); err != nil {
+			http.Error(w, "Could not enroll user", http.StatusInternalServerError)
+			return
+		}
+		participant, err = h.ParticipantRepo.GetUserParticipant(competition.ID, input.UserID)
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(participant)
+}
+
+// RemoveParticipantHandler hanterar DELETE /competitions/{id}/participants/{participantId}
+// Skaparen och admins kan ta bort alla anmälningar, andra bara sin egen.
+func (h *CompetitionHandler) RemoveParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	participantID, err := strconv.ParseInt(mux.Vars(r)["participantId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid participant ID", http.StatusBadRequest)
+		return
+	}
+
+	participant, err := h.ParticipantRepo.GetParticipant(competition.ID, participantID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if participant == nil {
+		http.Error(w, "Participant not found", http.StatusNotFound)
+		return
+	}
+	isSelf := participant.UserID != nil && *participant.UserID == userID
+	if !isSelf && !h.requireOwnerOrAdmin(w, competition, userID, "Only the creator or an admin can remove other participants") {
+		return
+	}
+
+	if err := h.ParticipantRepo.RemoveParticipant(competition.ID, participantID); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JoinCompetitionHandler hanterar POST /competitions/{id}/join för den inloggade användaren.
+// I en lagtävling kan body:n innehålla {"teamId": 1} för att anmäla sitt eget team,
+// annars ångras ett tidigare opt-out.
+func (h *CompetitionHandler) JoinCompetitionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if competition.Status == "ended" {
+		http.Error(w, "Competition has ended", http.StatusConflict)
+		return
+	}
+
+	if competition.CompetitionType == models.CompetitionTypeTeam {
+		var input struct {
+			TeamID int64 `json:"teamId"`
+		}
+		// En tom body är tillåten
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if input.TeamID > 0 {
+			isMember, err := h.UserTeamRepo.IsUserInTeam(userID, input.TeamID)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !isMember {
+				http.Error(w, "You can only enroll teams you are a member of", http.StatusForbidden)
+				return
+			}
+			if competition.InviteOnly {
+				invitation, err := h.ParticipantRepo.GetTeamParticipant(competition.ID, input.TeamID)
+				if err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				if invitation == nil {
+					http.Error(w, "Competition is invite-only", http.StatusForbidden)
+					return
+				}
+			}
+			if err := h.ParticipantRepo.SetTeamStatus(competition.ID, input.TeamID, models.ParticipantStatusJoined); err != nil {
+				http.Error(w, "Could not enroll team", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Ångra ett eventuellt opt-out så att användarens poäng räknas för teamet igen
+		if err := h.ParticipantRepo.RemoveUser(competition.ID, userID); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if competition.InviteOnly {
+		invitation, err := h.ParticipantRepo.GetUserParticipant(competition.ID, userID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if invitation == nil {
+			http.Error(w, "Competition is invite-only", http.StatusForbidden)
+			return
+		}
+	}
+	if err := h.ParticipantRepo.SetUserStatus(competition.ID, userID, models.ParticipantStatusJoined); err != nil {
+		http.Error(w, "Could not join competition", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LeaveCompetitionHandler hanterar POST /competitions/{id}/leave för den inloggade användaren.
+// Anmälan sparas med status "left" så att användaren inte räknas, inte heller via sitt team.
+func (h *CompetitionHandler) LeaveCompetitionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+	if !ok {
+		http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+		return
+	}
+	competition, ok := h.getCompetitionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if competition.Status == "ended" {
+		http.Error(w, "Competition has ended", http.StatusConflict)
+		return
+	}
+
+	// I en öppen tävling är alla med utan att ha anmält sig, där räcker det att spara att man har lämnat
+	if competition.CompetitionType == models.CompetitionTypeIndividual && competition.InviteOnly {
+		participant, err := h.ParticipantRepo.GetUserParticipant(competition.ID, userID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if participant == nil {
+			http.Error(w, "You are not taking part in this competition", http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := h.ParticipantRepo.SetUserStatus(competition.ID, userID, models.ParticipantStatusLeft); err != nil {
+		http.Error(w, "Could not leave competition", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
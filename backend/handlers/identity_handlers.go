@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/database"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// IdentityHandler kopplar användare i andra aktivitetskällor (t.ex. commit-författare i Git) till våra användare.
+type IdentityHandler struct {
+	Repo     *database.IdentityRepository
+	UserRepo *database.UserRepository
+}
+
+// GetUserIdentitiesHandler hanterar GET /users/{id}/identities
+func (h *IdentityHandler) GetUserIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	identities, err := h.Repo.GetIdentitiesByUserID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identities)
+}
+
+// LinkIdentityHandler hanterar POST /users/{id}/identities
+// Framtida aktiviteter från identiteten registreras på användaren. Redan registrerade aktiviteter flyttas inte.
+func (h *IdentityHandler) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Source     string `json:"source"`
+		ExternalID string `json:"externalId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input.Source = strings.TrimSpace(input.Source)
+	input.ExternalID = strings.TrimSpace(input.ExternalID)
+	var errs []FieldError
+	if input.Source == "" {
+		errs = append(errs, FieldError{"source", "source is required"})
+	}
+	if input.ExternalID == "" {
+		errs = append(errs, FieldError{"externalId", "externalId is required"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.Repo.LinkIdentity(input.Source, input.ExternalID, userID); err != nil {
+		http.Error(w, "Could not link identity", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlinkIdentityHandler hanterar DELETE /users/{id}/identities/{source}/{externalId}
+func (h *IdentityHandler) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.UnlinkIdentity(userID, vars["source"], vars["externalId"]); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
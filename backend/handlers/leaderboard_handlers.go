@@ -17,7 +17,10 @@ func (h *LeaderboardHandler) GetLeaderboardByDate(w http.ResponseWriter, r *http
 		return
 	}
 
-	leaderboard, err := h.Repo.GetLeaderboardByDate(date)
+	// Valfritt: ?space=KEY begränsar till ett Confluence-space
+	space := r.URL.Query().Get("space")
+
+	leaderboard, err := h.Repo.GetLeaderboardByDate(date, space)
 	if err != nil {
 		http.Error(w, "Failed to fetch leaderboard: "+err.Error(), http.StatusInternalServerError)
 		return
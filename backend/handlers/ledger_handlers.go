@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gamification-api/backend/contextkeys"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// LedgerHandler visar poängliggaren och låter admins ge, dra av och återföra poäng.
+type LedgerHandler struct {
+	Repo     *database.LedgerRepository
+	UserRepo *database.UserRepository
+}
+
+// GetUserLedgerHandler hanterar GET /users/{id}/ledger
+// Returnerar alla poängförändringar för en användare, nyast först.
+func (h *LedgerHandler) GetUserLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.Repo.GetEntriesByUserID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AdjustPointsHandler hanterar POST /users/{id}/points
+// Ger (positiva poäng) eller drar av (negativa poäng) poäng manuellt. Skälet är obligatoriskt.
+func (h *LedgerHandler) AdjustPointsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Points int    `json:"points"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input.Reason = strings.TrimSpace(input.Reason)
+	var errs []FieldError
+	if input.Points == 0 {
+		errs = append(errs, FieldError{"points", "points must not be 0"})
+	}
+	if input.Reason == "" {
+		errs = append(errs, FieldError{"reason", "reason is required"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	entry := &models.LedgerEntry{
+		UserID:         userID,
+		EntryType:      models.LedgerEntryAdjustment,
+		Points:         input.Points,
+		LifetimePoints: input.Points,
+		Reason:         input.Reason,
+		ActorUserID:    actorFromRequest(r),
+	}
+	if err := h.Repo.AddEntry(entry); err != nil {
+		http.Error(w, "Could not adjust points", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// ReverseEntryHandler hanterar POST /ledger/{id}/reverse
+// Återför en rad med en ny rad med motsatta poäng. Raden själv ändras aldrig.
+func (h *LedgerHandler) ReverseEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ledger entry ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		writeValidationErrors(w, []FieldError{{"reason", "reason is required"}})
+		return
+	}
+
+	reversal, err := h.Repo.ReverseEntry(id, input.Reason, actorFromRequest(r))
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Ledger entry not found", http.StatusNotFound)
+		return
+	case err == database.ErrEntryReversed:
+		http.Error(w, "Ledger entry is already reversed", http.StatusConflict)
+		return
+	case err == database.ErrEntryNotReversible:
+		http.Error(w, "Ledger entry cannot be reversed", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Could not reverse ledger entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
+
+// actorFromRequest är den inloggade användaren, eller nil om anropet saknar JWT.
+func actorFromRequest(r *http.Request) *int64 {
+	if userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64); ok {
+		return &userID
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/badges"
+	"gamification-api/backend/contextkeys"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ModerationHandler struct {
+	Repo          *database.ModerationRepository
+	UserStatsRepo *database.UserStatsRepository
+	Badges        *badges.Engine
+}
+
+// GetFlagsHandler hanterar GET /moderation/flags
+// Returnerar modereringskön (status pending) om inget annat anges med ?status=, t.ex. ?status=all.
+func (h *ModerationHandler) GetFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.FlagStatusPending
+	case "all":
+		status = ""
+	case models.FlagStatusPending, models.FlagStatusApproved, models.FlagStatusRejected:
+	default:
+		writeValidationErrors(w, []FieldError{{"status", "status must be pending, approved, rejected or all"}})
+		return
+	}
+
+	flags, err := h.Repo.GetFlags(status)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flags)
+}
+
+// GetFlagHandler hanterar GET /moderation/flags/{id}
+func (h *ModerationHandler) GetFlagHandler(w http.ResponseWriter, r *http.Request) {
+	flag := h.getFlagFromRequest(w, r)
+	if flag == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+// ApproveFlagHandler hanterar POST /moderation/flags/{id}/approve
+// Aktiviteten var ärlig: användaren får de innehållna poängen och aktiviteten räknas i statistiken.
+func (h *ModerationHandler) ApproveFlagHandler(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, true)
+}
+
+// RejectFlagHandler hanterar POST /moderation/flags/{id}/reject
+// Aktiviteten ger inga poäng och räknas inte mot badges.
+func (h *ModerationHandler) RejectFlagHandler(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, false)
+}
+
+func (h *ModerationHandler) review(w http.ResponseWriter, r *http.Request, approve bool) {
+	flag := h.getFlagFromRequest(w, r)
+	if flag == nil {
+		return
+	}
+	reviewerID, _ := r.Context().Value(contextkeys.UserContextKey).(int64)
+
+	if err := h.Repo.ReviewFlag(flag.ID, approve, reviewerID); err != nil {
+		if err == database.ErrFlagReviewed {
+			http.Error(w, "Flag is already reviewed", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not review flag", http.StatusInternalServerError)
+		return
+	}
+
+	// Godkända aktiviteter ska räknas mot user_stats och badges
+	if approve {
+		if err := h.UserStatsRepo.RebuildStatsForUser(flag.UserID); err != nil {
+			log.Printf("Kunde inte räkna om user_stats efter granskning av flagga %d: %v", flag.ID, err)
+		}
+		if _, err := h.Badges.CheckUser(flag.UserID); err != nil {
+			log.Printf("Kunde inte utvärdera badges efter granskning av flagga %d: %v", flag.ID, err)
+		}
+	}
+
+	flag, err := h.Repo.GetFlag(flag.ID)
+	if err != nil || flag == nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+func (h *ModerationHandler) getFlagFromRequest(w http.ResponseWriter, r *http.Request) *models.ActivityFlag {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid flag ID", http.StatusBadRequest)
+		return nil
+	}
+
+	flag, err := h.Repo.GetFlag(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	if flag == nil {
+		http.Error(w, "Flag not found", http.StatusNotFound)
+		return nil
+	}
+	return flag
+}
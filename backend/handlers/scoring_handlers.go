@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/contextkeys"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ScoringRuleHandler struct {
+	Repo *database.ScoringRuleRepository
+}
+
+// GetAllRulesHandler hanterar GET /scoring-rules
+// Returnerar de aktiva reglerna, eller alla versioner med ?includeInactive=true.
+func (h *ScoringRuleHandler) GetAllRulesHandler(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("includeInactive") == "true"
+
+	rules, err := h.Repo.GetAllRules(includeInactive)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// GetRuleHandler hanterar GET /scoring-rules/{id}
+func (h *ScoringRuleHandler) GetRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := h.getRuleFromRequest(w, r)
+	if rule == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// CreateRuleHandler hanterar POST /scoring-rules
+// Sparar en ny version av regeln för en aktivitetstyp. Den gäller direkt för nya aktiviteter,
+// redan registrerade aktiviteter behåller sina poäng.
+func (h *ScoringRuleHandler) CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ActivityType      string   `json:"activityType"`
+		BasePoints        *int     `json:"basePoints"`
+		DiffThresholds    []int    `json:"diffThresholds"`
+		MaxPoints         *int     `json:"maxPoints"`
+		DailyCap          *int     `json:"dailyCap"`
+		WeeklyCap         *int     `json:"weeklyCap"`
+		DiminishingFactor *float64 `json:"diminishingFactor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var errs []FieldError
+	if !isKnownActivityType(input.ActivityType) {
+		errs = append(errs, FieldError{"activityType", "unknown activity type: " + input.ActivityType})
+	}
+	if input.BasePoints == nil || *input.BasePoints < 0 {
+		errs = append(errs, FieldError{"basePoints", "basePoints is required and must not be negative"})
+	}
+	for i, limit := range input.DiffThresholds {
+		if limit <= 0 || (i > 0 && limit <= input.DiffThresholds[i-1]) {
+			errs = append(errs, FieldError{"diffThresholds", "diffThresholds must be positive and strictly increasing"})
+			break
+		}
+	}
+	if input.MaxPoints != nil && *input.MaxPoints < 0 {
+		errs = append(errs, FieldError{"maxPoints", "maxPoints must not be negative"})
+	}
+	if input.DailyCap != nil && *input.DailyCap < 0 {
+		errs = append(errs, FieldError{"dailyCap", "dailyCap must not be negative"})
+	}
+	if input.WeeklyCap != nil && *input.WeeklyCap < 0 {
+		errs = append(errs, FieldError{"weeklyCap", "weeklyCap must not be negative"})
+	}
+	if input.DiminishingFactor != nil && (*input.DiminishingFactor <= 0 || *input.DiminishingFactor > 1) {
+		errs = append(errs, FieldError{"diminishingFactor", "diminishingFactor must be greater than 0 and at most 1"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	rule := &models.ScoringRule{
+		ActivityType:      input.ActivityType,
+		BasePoints:        *input.BasePoints,
+		DiffThresholds:    input.DiffThresholds,
+		MaxPoints:         input.MaxPoints,
+		DailyCap:          input.DailyCap,
+		WeeklyCap:         input.WeeklyCap,
+		DiminishingFactor: input.DiminishingFactor,
+	}
+	if rule.DiffThresholds == nil {
+		rule.DiffThresholds = []int{}
+	}
+	if userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64); ok {
+		rule.CreatedByUserID = &userID
+	}
+
+	if err := h.Repo.CreateRuleVersion(rule); err != nil {
+		http.Error(w, "Could not save scoring rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ActivateRuleHandler hanterar POST /scoring-rules/{id}/activate
+// Gör en tidigare version aktiv igen, t.ex. för att backa en ändring.
+func (h *ScoringRuleHandler) ActivateRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := h.getRuleFromRequest(w, r)
+	if rule == nil {
+		return
+	}
+
+	if err := h.Repo.ActivateRule(rule.ID); err != nil {
+		http.Error(w, "Could not activate scoring rule", http.StatusInternalServerError)
+		return
+	}
+	rule.Active = true
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *ScoringRuleHandler) getRuleFromRequest(w http.ResponseWriter, r *http.Request) *models.ScoringRule {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid scoring rule ID", http.StatusBadRequest)
+		return nil
+	}
+
+	rule, err := h.Repo.GetRuleByID(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	if rule == nil {
+		http.Error(w, "Scoring rule not found", http.StatusNotFound)
+		return nil
+	}
+	return rule
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type SeasonHandler struct {
+	Repo *database.SeasonRepository
+}
+
+// GetAllSeasonsHandler hanterar GET /seasons
+func (h *SeasonHandler) GetAllSeasonsHandler(w http.ResponseWriter, r *http.Request) {
+	seasons, err := h.Repo.GetAllSeasons()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(seasons)
+}
+
+// GetCurrentSeasonHandler hanterar GET /seasons/current
+func (h *SeasonHandler) GetCurrentSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	season, err := h.Repo.GetCurrentSeason()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if season == nil {
+		http.Error(w, "No season is active", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(season)
+}
+
+// GetSeasonHandler hanterar GET /seasons/{id}
+func (h *SeasonHandler) GetSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	season := h.getSeasonFromRequest(w, r)
+	if season == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(season)
+}
+
+// GetStandingsHandler hanterar GET /seasons/{id}/standings
+// För arkiverade säsonger är det slutställningen, annars ställningen just nu.
+func (h *SeasonHandler) GetStandingsHandler(w http.ResponseWriter, r *http.Request) {
+	season := h.getSeasonFromRequest(w, r)
+	if season == nil {
+		return
+	}
+
+	standings, err := h.Repo.GetStandings(season)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Season    *models.Season          `json:"season"`
+		Standings []models.SeasonStanding `json:"standings"`
+	}{season, standings})
+}
+
+// CreateSeasonHandler hanterar POST /seasons
+func (h *SeasonHandler) CreateSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string     `json:"name"`
+		StartDate *time.Time `json:"startDate"`
+		EndDate   *time.Time `json:"endDate"`
+		AutoRenew bool       `json:"autoRenew"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	season := &models.Season{
+		Name:            strings.TrimSpace(input.Name),
+		AutoRenew:       input.AutoRenew,
+		CreatedByUserID: actorFromRequest(r),
+	}
+	var errs []FieldError
+	if season.Name == "" {
+		errs = append(errs, FieldError{"name", "name is required"})
+	}
+	if input.StartDate == nil {
+		errs = append(errs, FieldError{"startDate", "startDate is required"})
+	}
+	if input.EndDate == nil {
+		errs = append(errs, FieldError{"endDate", "endDate is required"})
+	}
+	if input.StartDate != nil && input.EndDate != nil && !input.EndDate.After(*input.StartDate) {
+		errs = append(errs, FieldError{"endDate", "endDate must be after startDate"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	season.StartDate, season.EndDate = input.StartDate.UTC(), input.EndDate.UTC()
+
+	if err := h.Repo.CreateSeason(season); err != nil {
+		if err == database.ErrSeasonOverlap {
+			http.Error(w, "Season overlaps another season", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not create season", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(season)
+}
+
+// DeleteSeasonHandler hanterar DELETE /seasons/{id}
+// Bara säsonger som inte har arkiverats kan tas bort.
+func (h *SeasonHandler) DeleteSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid season ID", http.StatusBadRequest)
+		return
+	}
+
+	switch err := h.Repo.DeleteSeason(id); {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Season not found", http.StatusNotFound)
+	case err == database.ErrSeasonArchived:
+		http.Error(w, "Archived seasons cannot be deleted", http.StatusConflict)
+	case err != nil:
+		http.Error(w, "Could not delete season", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *SeasonHandler) getSeasonFromRequest(w http.ResponseWriter, r *http.Request) *models.Season {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid season ID", http.StatusBadRequest)
+		return nil
+	}
+
+	season, err := h.Repo.GetSeason(id)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	if season == nil {
+		http.Error(w, "Season not found", http.StatusNotFound)
+		return nil
+	}
+	return season
+}
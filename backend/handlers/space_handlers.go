@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type SpaceHandler struct {
+	Repo *database.SpaceRepository
+}
+
+// GetAllSpacesHandler hanterar GET /spaces
+func (h *SpaceHandler) GetAllSpacesHandler(w http.ResponseWriter, r *http.Request) {
+	spaces, err := h.Repo.GetAllSpaces()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spaces)
+}
+
+// UpsertSpaceHandler hanterar POST /spaces
+// Lägger till ett space i synkroniseringen, eller uppdaterar ett befintligt. Gäller från nästa synk.
+func (h *SpaceHandler) UpsertSpaceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Key     string `json:"key"`
+		Name    string `json:"name"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	input.Key = strings.TrimSpace(input.Key)
+	if !models.ValidSpaceKey(input.Key) {
+		writeValidationErrors(w, []FieldError{{"key", "key must be a Confluence space key (letters, digits, _, ~ and -)"}})
+		return
+	}
+
+	space := &models.ConfluenceSpace{
+		Key:     input.Key,
+		Name:    strings.TrimSpace(input.Name),
+		Enabled: input.Enabled == nil || *input.Enabled,
+	}
+	if err := h.Repo.UpsertSpace(space); err != nil {
+		http.Error(w, "Could not save space", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(space)
+}
+
+// DeleteSpaceHandler hanterar DELETE /spaces/{key}
+func (h *SpaceHandler) DeleteSpaceHandler(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if err := h.Repo.DeleteSpace(key); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -6,6 +6,7 @@ import (
 	"gamification-api/backend/contextkeys"
 	"gamification-api/backend/database"
 	"gamification-api/backend/models"
+	"gamification-api/backend/streaks"
 	"net/http"
 	"strconv"
 
@@ -15,6 +16,7 @@ import (
 type UserHandler struct {
 	Repo *database.UserRepository
 	UserStatsRepo *database.UserStatsRepository
+	Streaks *streaks.Tracker
 }
 
 func (h *UserHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
@@ -169,7 +171,20 @@ func (h *UserHandler) GetUserStatsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	stats, err := h.UserStatsRepo.GetUserStatsByUserID(id)
+	// Valfritt: ?space=KEY ger statistik för ett enskilt Confluence-space
+	var stats *models.UserStats
+	if space := r.URL.Query().Get("space"); space != "" {
+		stats, err = h.UserStatsRepo.GetUserStatsByUserIDAndSpace(id, space)
+	} else {
+		stats, err = h.UserStatsRepo.GetUserStatsByUserID(id)
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Sviterna räknas fram ur aktiviteterna, med samma space-filter
+	stats.Streaks, err = h.Streaks.ForUser(id, r.URL.Query().Get("space"))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
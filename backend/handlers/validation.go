@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// FieldError beskriver varför ett enskilt fält i en request är ogiltigt.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// writeValidationErrors skickar ett 400-svar som listar alla ogiltiga fält.
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	response := struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}{
+		Error:  "Validation failed",
+		Fields: errs,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,181 @@
+package confluence
+
+import (
+	"errors"
+	"fmt"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"log"
+	"sync"
+)
+
+// ErrBackfillRunning returneras när ett jobb som redan körs startas igen, i den här eller en annan process.
+var ErrBackfillRunning = errors.New("backfill-jobbet körs redan")
+
+// Backfiller läser in historisk aktivitet från Confluence: alla versioner och kommentarer
+// på varje sida i ett space, med sina ursprungliga tidpunkter.
+type Backfiller struct {
+	Client       *Client
+	Repositories Repositories
+	JobRepo      *database.BackfillRepository
+
+	// syncMu är tjänstens lås, så att polling och webhooks inte registrerar samma ändring samtidigt
+	syncMu *sync.Mutex
+}
+
+// NewBackfiller skapar en Backfiller som delar klient, repositories och lås med tjänsten.
+func NewBackfiller(service *Service, jobRepo *database.BackfillRepository) *Backfiller {
+	return &Backfiller{
+		Client:       service.Client,
+		Repositories: service.Repositories,
+		JobRepo:      jobRepo,
+		syncMu:       &service.mu,
+	}
+}
+
+// Start kör ett jobb i bakgrunden.
+func (b *Backfiller) Start(jobID int64) error {
+	job, err := b.claim(jobID)
+	if err != nil || job == nil {
+		return err
+	}
+
+	go func() {
+		if err := b.run(job); err != nil {
+			log.Printf("Backfill-jobb %d misslyckades: %v", jobID, err)
+		}
+	}()
+	return nil
+}
+
+// Run kör ett jobb och väntar tills det är klart. Sidor som jobbet redan har gått igenom
+// hoppas över, så ett avbrutet jobb kan köras igen för att fortsätta där det slutade.
+func (b *Backfiller) Run(jobID int64) error {
+	job, err := b.claim(jobID)
+	if err != nil || job == nil {
+		return err
+	}
+
+	return b.run(job)
+}
+
+// claim tar jobbet i databasen. Returnerar nil utan fel om jobbet redan är klart.
+func (b *Backfiller) claim(jobID int64) (*models.BackfillJob, error) {
+	claimed, err := b.JobRepo.ClaimJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := b.JobRepo.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case job == nil:
+		return nil, fmt.Errorf("backfill-jobb %d finns inte", jobID)
+	case claimed:
+		return job, nil
+	case job.Status == models.BackfillStatusCompleted:
+		return nil, nil
+	default:
+		return nil, ErrBackfillRunning
+	}
+}
+
+func (b *Backfiller) run(job *models.BackfillJob) error {
+	if err := b.backfillSpace(job); err != nil {
+		if statusErr := b.JobRepo.SetStatus(job.ID, models.BackfillStatusFailed, err.Error()); statusErr != nil {
+			log.Printf("FEL vid uppdatering av backfill-jobb %d: %v", job.ID, statusErr)
+		}
+		return err
+	}
+
+	return b.JobRepo.SetStatus(job.ID, models.BackfillStatusCompleted, "")
+}
+
+func (b *Backfiller) backfillSpace(job *models.BackfillJob) error {
+	log.Printf("Backfill-jobb %d startar för space %s", job.ID, job.SpaceKey)
+
+	pageResponse, err := b.Client.GetPages(job.SpaceKey)
+	if err != nil {
+		return err
+	}
+
+	done, err := b.JobRepo.GetDonePages(job.ID)
+	if err != nil {
+		return err
+	}
+
+	userCache := make(map[string]UserResponse)
+	for _, content := range pageResponse.Results {
+		if done[content.ID] {
+			continue
+		}
+
+		page := content
+		if page.Space == nil {
+			page.Space = &Space{Key: job.SpaceKey}
+		}
+
+		// Låset tas per sida så att pollingen inte blir stående under hela jobbet
+		b.syncMu.Lock()
+		created, err := b.backfillPage(job, page, userCache)
+		b.syncMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("sida %s: %w", page.ID, err)
+		}
+		if err := b.JobRepo.MarkPageDone(job.ID, page.ID, created); err != nil {
+			return err
+		}
+	}
+
+	// Aktiviteterna kan ha registrerats i en annan ordning än de skedde, så räkna om allt på slutet
+	if err := b.Repositories.UserStatsRepo.RebuildUserStats(); err != nil {
+		return fmt.Errorf("kunde inte räkna om user_stats: %w", err)
+	}
+	if _, err := b.Repositories.Recorder.Badges.CheckAllUsers(); err != nil {
+		return fmt.Errorf("kunde inte utvärdera badges: %w", err)
+	}
+
+	log.Printf("Backfill-jobb %d klart för space %s", job.ID, job.SpaceKey)
+	return nil
+}
+
+// backfillPage går igenom alla versioner och kommentarer på en sida och registrerar de
+// som ligger inom jobbets intervall. Redan registrerade aktiviteter hoppas över.
+func (b *Backfiller) backfillPage(job *models.BackfillJob, page Content, userCache map[string]UserResponse) (int, error) {
+	var created int
+
+	var previousContent string
+	for number := 1; number <= page.Version.Number; number++ {
+		pageVersion, err := b.Client.GetPageVersion(page.ID, number)
+		if err != nil {
+			return created, err
+		}
+
+		if job.InRange(pageVersion.Version.CreatedAt) {
+			ok, err := syncPageVersion(b.Client, b.Repositories, page, pageVersion.Version, previousContent, pageVersion.Content, userCache)
+			if err != nil {
+				return created, err
+			}
+			if ok {
+				created++
+			}
+		}
+		previousContent = pageVersion.Content
+	}
+
+	comments, err := b.Client.GetAllComments(page)
+	if err != nil {
+		return created, err
+	}
+
+	var inRange []Content
+	for _, comment := range comments {
+		if job.InRange(comment.Version.CreatedAt) {
+			inRange = append(inRange, comment)
+		}
+	}
+	n, err := syncComments(b.Client, b.Repositories, page, inRange, userCache)
+	return created + n, err
+}
@@ -3,9 +3,11 @@ package confluence
 import (
 	"encoding/json"
 	"fmt"
+	"gamification-api/backend/models"
 	"io/ioutil"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"time"
 )
 
@@ -27,36 +29,115 @@ func NewClient(baseURL, email, apiToken string) *Client {
 	}
 }
 
-// GetPages hämtar de senaste sidorna från Confluence.
-func (c *Client) GetPages() (*PageResponse, error) {
-	spaceKey := "teambfa0a452d69a428ba70ff3d22ef01502"
+// pageLimit är hur många resultat som begärs per anrop. Confluence kan returnera färre.
+const pageLimit = 50
 
-	url := fmt.Sprintf("%s/rest/api/content?spaceKey=%s&limit=50&start=0&expand=version.by,children.comment,children.comment.version.by,extensions.resolution", c.BaseURL, spaceKey) // c.SpaceKey måste definieras
+// pageExpand är de fält som expanderas när sidor hämtas.
+const pageExpand = "version.by,space,ancestors,children.comment,children.comment.version.by,extensions.resolution"
 
-	req, err := http.NewRequest("GET", url, nil)
+// GetPages hämtar alla sidor i ett space från Confluence, sida för sida tills resultaten tar slut.
+func (c *Client) GetPages(spaceKey string) (*PageResponse, error) {
+	url := fmt.Sprintf("%s/rest/api/content?spaceKey=%s&limit=%d&start=0&expand=%s", c.BaseURL, neturl.QueryEscape(spaceKey), pageLimit, pageExpand)
+
+	results, err := c.getAllResults(url, "GetPages")
 	if err != nil {
-		return nil, fmt.Errorf("kunde inte skapa request: %w", err)
+		return nil, err
 	}
 
-	req.SetBasicAuth(c.Email, c.APIToken)
-	req.Header.Set("Accept", "application/json")
+	return &PageResponse{Results: results, Start: 0, Limit: len(results), Size: len(results)}, nil
+}
 
-	resp, err := c.HTTP.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("kunde inte utföra request: %w", err)
+// cursorOverlap är hur långt före cursorn sökningen börjar. CQL räknar bara i hela minuter,
+// så ändringar som redan är registrerade kan komma tillbaka och filtreras bort av dubblettkontrollen.
+const cursorOverlap = 2 * time.Minute
+
+// changedContentExpand är de fält som expanderas i sökresultaten. Kommentarer får med sin sida.
+const changedContentExpand = pageExpand + ",container,container.space,container.ancestors"
+
+// SearchChangedContent hämtar sidor, blogginlägg och kommentarer i ett space som ändrats efter since,
+// äldst först. Om since är nil hämtas allt innehåll i spacet.
+func (c *Client) SearchChangedContent(spaceKey string, since *time.Time) ([]Content, error) {
+	// Nyckeln skrivs in i CQL, spaces som sparades innan nycklarna validerades kan vara ogiltiga
+	if !models.ValidSpaceKey(spaceKey) {
+		return nil, fmt.Errorf("ogiltig space-nyckel %q", spaceKey)
 	}
-	defer resp.Body.Close()
+	cql := fmt.Sprintf(`space = "%s" AND type in (page, blogpost, comment)`, spaceKey)
+	if since != nil {
+		// now("-Nm") gör att vi slipper bry oss om vilken tidszon Confluence tolkar datum i
+		minutes := int(time.Since(since.Add(-cursorOverlap)).Minutes()) + 1
+		cql += fmt.Sprintf(` AND lastmodified >= now("-%dm")`, minutes)
+	}
+	cql += " ORDER BY lastmodified ASC"
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("oväntad statuskod från Confluence (GetPages): %s", resp.Status)
+	url := fmt.Sprintf("%s/rest/api/content/search?cql=%s&limit=%d&expand=%s", c.BaseURL, neturl.QueryEscape(cql), pageLimit, changedContentExpand)
+	return c.getAllResults(url, "SearchChangedContent")
+}
+
+// GetAllComments returnerar alla kommentarer på en sida. De inbäddade kommentarerna
+// (children.comment) används om de är kompletta, annars hämtas alla via child/comment.
+func (c *Client) GetAllComments(page Content) ([]Content, error) {
+	if page.Children == nil || page.Children.Comment == nil {
+		return nil, nil
 	}
+	// Inbäddade listor saknar ibland next-länk, så en full lista räknas också som avkortad
+	embedded := page.Children.Comment
+	truncated := embedded.Links.Next != "" || (embedded.Limit > 0 && embedded.Size >= embedded.Limit)
+	if !truncated {
+		return embedded.Results, nil
+	}
+
+	url := fmt.Sprintf("%s/rest/api/content/%s/child/comment?depth=all&limit=%d&start=0&expand=version.by,extensions.resolution", c.BaseURL, page.ID, pageLimit)
+	return c.getAllResults(url, "GetAllComments")
+}
 
-	var pageResponse PageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&pageResponse); err != nil {
-		return nil, fmt.Errorf("kunde inte avkoda JSON-svar (GetPages): %w", err)
+// getAllResults hämtar en paginerad lista och följer _links.next tills den tar slut.
+func (c *Client) getAllResults(url, operation string) ([]Content, error) {
+	var all []Content
+
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("kunde inte skapa request (%s): %w", operation, err)
+		}
+
+		req.SetBasicAuth(c.Email, c.APIToken)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("kunde inte utföra request (%s): %w", operation, err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("oväntad statuskod från Confluence (%s): %s", operation, resp.Status)
+		}
+
+		var page PageResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("kunde inte avkoda JSON-svar (%s): %w", operation, err)
+		}
+
+		all = append(all, page.Results...)
+
+		url = ""
+		if page.Links.Next != "" && len(page.Results) > 0 {
+			url = c.nextURL(page.Links)
+		}
 	}
 
-	return &pageResponse, nil
+	return all, nil
+}
+
+// nextURL bygger den absoluta URL:en för nästa sida i en paginerad lista.
+func (c *Client) nextURL(links Links) string {
+	base := links.Base
+	if base == "" {
+		base = c.BaseURL
+	}
+	return base + links.Next
 }
 
 // GetUserDetails hämtar detaljer för en specifik användare via deras Atlassian-ID.
@@ -127,14 +208,15 @@ func (c *Client) GetCommentResolutionHistory(commentID string) (*ResolutionResul
 
 	// Vi har hittat resolvaren (den som gjorde den sista versionen)
 	return &ResolutionResult{
-		AccountID: accountID,
-		Found:     true,
+		AccountID:  accountID,
+		ResolvedAt: historyResponse.LastUpdated.When,
+		Found:      true,
 	}, nil
 }
 
 // GetCommentDetails hämtar en kommentar separat för att få dess garanterade resolution status.
 func (c *Client) GetCommentDetails(commentID string) (*Content, error) {
-	url := fmt.Sprintf("%s/rest/api/content/%s?expand=extensions.resolution,version.by", c.BaseURL, commentID)
+	url := fmt.Sprintf("%s/rest/api/content/%s?expand=extensions.resolution,version.by,body.storage,history", c.BaseURL, commentID)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -164,20 +246,67 @@ func (c *Client) GetCommentDetails(commentID string) (*Content, error) {
 	return &content, nil
 }
 
+// GetContent hämtar en sida eller kommentar med samma expanderade fält som synken använder.
+// För kommentarer följer sidan de sitter på med i Container.
+func (c *Client) GetContent(contentID string) (*Content, error) {
+	url := fmt.Sprintf("%s/rest/api/content/%s?expand=%s", c.BaseURL, neturl.PathEscape(contentID), changedContentExpand)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("kunde inte skapa request för innehåll %s: %w", contentID, err)
+	}
+
+	req.SetBasicAuth(c.Email, c.APIToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kunde inte utföra request för innehåll %s: %w", contentID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oväntad statuskod från Confluence (GetContent) för ID %s: %s", contentID, resp.Status)
+	}
+
+	var content Content
+	if err := json.NewDecoder(resp.Body).Decode(&content); err != nil {
+		return nil, fmt.Errorf("kunde inte avkoda JSON-svar (GetContent) för ID %s: %w", contentID, err)
+	}
+
+	return &content, nil
+}
+
+// PageVersion är en specifik version av en sida: vem som sparade den, när, och innehållet.
+type PageVersion struct {
+	Version Version
+	Content string
+}
+
 // GetPageVersionContent hämtar innehållet för en viss version av en sida.
 func (c *Client) GetPageVersionContent(pageID string, versionNumber int) (string, error) {
+	pageVersion, err := c.GetPageVersion(pageID, versionNumber)
+	if err != nil {
+		return "", err
+	}
+	return pageVersion.Content, nil
+}
+
+// GetPageVersion hämtar en viss version av en sida inklusive författare och tidpunkt.
+// Är versionNumber 0 hämtas den senaste versionen.
+func (c *Client) GetPageVersion(pageID string, versionNumber int) (*PageVersion, error) {
 	var url string
 	if versionNumber > 0 {
 		// historisk version
-		url = fmt.Sprintf("%s/rest/api/content/%s?version=%d&expand=body.storage,version", c.BaseURL, pageID, versionNumber)
+		url = fmt.Sprintf("%s/rest/api/content/%s?version=%d&expand=body.storage,version,version.by", c.BaseURL, pageID, versionNumber)
 	} else {
 		// senaste versionen
-		url = fmt.Sprintf("%s/rest/api/content/%s?expand=body.storage,version", c.BaseURL, pageID)
+		url = fmt.Sprintf("%s/rest/api/content/%s?expand=body.storage,version,version.by", c.BaseURL, pageID)
 	}
 	// Skapa request
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Använd Client-strukturen för Basic Auth
@@ -187,27 +316,28 @@ func (c *Client) GetPageVersionContent(pageID string, versionNumber int) (string
 	// Skicka request
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("Confluence API-fel: %s", string(body))
+		return nil, fmt.Errorf("Confluence API-fel: %s", string(body))
 	}
 
 	// Avkoda JSON
 	var page struct {
-		Body struct {
+		Version Version `json:"version"`
+		Body    struct {
 			Storage struct {
 				Value string `json:"value"`
 			} `json:"storage"`
 		} `json:"body"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-		return "", err
+		return nil, err
 	}
-	return page.Body.Storage.Value, nil
+	return &PageVersion{Version: page.Version, Content: page.Body.Storage.Value}, nil
 }
 
 // GetPageVersionContents hämtar både nuvarande och föregående versioners innehåll.
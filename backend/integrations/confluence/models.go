@@ -8,8 +8,14 @@ type PageResponse struct {
 	Start   int       `json:"start"`
 	Limit   int       `json:"limit"`
 	Size    int       `json:"size"`
-	// Ofta finns det en _links struct för paginering, men vi utgår från Start/Limit/Size
+	// Links.Next är satt så länge det finns fler resultat att hämta
+	Links Links `json:"_links"`
+}
 
+// Links innehåller pagineringslänkarna. Next är relativ till Base.
+type Links struct {
+	Base string `json:"base"`
+	Next string `json:"next"`
 }
 
 // Content representerar både en sida (page/blogpost) och en kommentar.
@@ -20,9 +26,35 @@ type Content struct {
 	Version Version `json:"version"`
 	// Children fångar upp inbäddade element som kommentarer när vi expanderar (children.comment)
 	Children *Children `json:"children,omitempty"`
-	// Lägg till Space, Status, Body om du behöver dem
+	// Body finns bara när body.storage expanderas (kommentarer i GetCommentDetails)
+	Body *Body `json:"body,omitempty"`
+	// History.CreatedBy är den som skrev innehållet, även om någon annan har ändrat det senare
+	History *History `json:"history,omitempty"`
 	// NYTT: Extensions för att fånga resolution status
 	Extensions *Extensions `json:"extensions,omitempty"`
+	// Space och Ancestors används av tävlingsregler som begränsar vilka spaces/sidträd som räknas
+	Space     *Space    `json:"space,omitempty"`
+	Ancestors []Content `json:"ancestors,omitempty"`
+	// Container är sidan som en kommentar sitter på (finns bara i sökresultat för kommentarer)
+	Container *Content `json:"container,omitempty"`
+}
+
+// Body är innehållet i storage-format.
+type Body struct {
+	Storage struct {
+		Value string `json:"value"`
+	} `json:"storage"`
+}
+
+// History innehåller vem som skapade innehållet.
+type History struct {
+	CreatedBy User `json:"createdBy"`
+}
+
+// Space är det Confluence-space som en sida ligger i.
+type Space struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
 }
 
 type Extensions struct {
@@ -63,6 +95,7 @@ type CommentContainer struct {
 	Start   int       `json:"start"`
 	Limit   int       `json:"limit"`
 	Size    int       `json:"size"`
+	Links   Links     `json:"_links"`
 }
 
 // UserResponse matchar API-svaret när vi hämtar detaljer om en användare separat.
@@ -81,14 +114,15 @@ type HistoryResponse struct {
 
 // VersionHistoryItem är ett objekt inuti historiken som inkluderar författare.
 type VersionHistoryItem struct {
-	By User `json:"by"` // Använder den befintliga User structen
-	// Ofta finns det en "when" (tidstämpel) här också
+	By   User      `json:"by"`   // Använder den befintliga User structen
+	When time.Time `json:"when"` // När uppdateringen gjordes
 }
 
 // ResolutionResult är den struct vi returnerar
 type ResolutionResult struct {
-	AccountID string
-	Found     bool
+	AccountID  string
+	ResolvedAt time.Time
+	Found      bool
 }
 
 // PageVersionResponse är den struct som vi använder för att hämta 2 olika versioner av pages
@@ -2,59 +2,43 @@ package confluence
 
 import (
 	"gamification-api/backend/database"
-	"log"
-	"time"
+	"gamification-api/backend/integrations"
+	"sync"
 )
 
-// Service hanterar den periodiska synkroniseringen av Confluence-data.
+// Service är Confluence som aktivitetskälla. Den körs periodiskt av integrations.Registry
+// och tar även emot webhooks.
 type Service struct {
 	Client       *Client
 	Repositories Repositories
-	ticker       *time.Ticker
-	stop         chan bool
+	// mu ser till att polling och webhooks inte registrerar samma ändring samtidigt
+	mu sync.Mutex
 }
 
 // NewService skapar och konfigurerar en ny synkroniseringstjänst.
-func NewService(client *Client, userRepo *database.UserRepository, activityRepo *database.ActivityRepository, userStatsRepo *database.UserStatsRepository, userBadgeRepo *database.UserBadgeRepository) *Service {
+func NewService(client *Client, recorder *integrations.Recorder, userRepo *database.UserRepository, activityRepo *database.ActivityRepository, userStatsRepo *database.UserStatsRepository, userBadgeRepo *database.UserBadgeRepository, spaceRepo *database.SpaceRepository) *Service {
 	return &Service{
 		Client: client,
 		Repositories: Repositories{
+			Recorder:      recorder,
 			UserRepo:      userRepo,
 			ActivityRepo:  activityRepo,
 			UserStatsRepo: userStatsRepo,
 			UserBadgeRepo: userBadgeRepo,
+			SpaceRepo:     spaceRepo,
 		},
-		stop: make(chan bool),
 	}
 }
 
-// Start initierar den periodiska synkroniseringen.
-// Den kör en synkronisering direkt och sedan en gång per intervall.
-func (s *Service) Start(interval time.Duration) {
-	log.Printf("Confluence-tjänsten startad. Synkroniserar var %v.", interval)
-	s.ticker = time.NewTicker(interval)
-
-	// Kör en go-rutin (en lättviktstråd) för att inte blockera resten av programmet.
-	go func() {
-		// Kör en synkronisering direkt vid start.
-		SyncActivities(s.Client, s.Repositories)
-
-		for {
-			select {
-			case <-s.ticker.C:
-				// Detta block körs varje gång "väckarklockan" ringer.
-				SyncActivities(s.Client, s.Repositories)
-			case <-s.stop:
-				// Om vi får en signal på stop-kanalen, avsluta loopen.
-				s.ticker.Stop()
-				return
-			}
-		}
-	}()
+// Name implementerar integrations.ActivitySource.
+func (s *Service) Name() string {
+	return integrations.SourceConfluence
 }
 
-// Stop avslutar den periodiska synkroniseringen.
-func (s *Service) Stop() {
-	log.Println("Stoppar Confluence-tjänsten...")
-	s.stop <- true
+// Sync implementerar integrations.ActivitySource och kör en synkronisering av alla spaces.
+func (s *Service) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	SyncActivities(s.Client, s.Repositories)
+	return nil
 }
@@ -1,143 +1,279 @@
 package confluence
 
 import (
-	"database/sql"
 	"fmt"
 	"gamification-api/backend/database"
+	"gamification-api/backend/integrations"
 	"gamification-api/backend/models"
+	"gamification-api/backend/scoring"
 	"log"
 	"strings"
+	"time"
 )
 
+// Repositories är det synken behöver. Själva registreringen av aktiviteter går via Recorder,
+// som delas med andra aktivitetskällor.
 type Repositories struct {
+	Recorder      *integrations.Recorder
 	UserRepo      *database.UserRepository
 	ActivityRepo  *database.ActivityRepository
 	UserStatsRepo *database.UserStatsRepository
 	UserBadgeRepo *database.UserBadgeRepository
+	SpaceRepo     *database.SpaceRepository
 }
 
 // SyncActivities är huvudfunktionen för att synkronisera data.
+// Alla aktiverade spaces i confluence_spaces synkas, ett i taget. Varje space har en
+// cursor (senast sedda ändring) så att bara innehåll som ändrats sedan förra körningen hämtas.
 func SyncActivities(client *Client, repos Repositories) {
 	log.Println("Startar Confluence-synkronisering...")
 
-	pageResponse, err := client.GetPages()
+	spaces, err := repos.SpaceRepo.GetEnabledSpaces()
 	if err != nil {
-		log.Printf("FEL vid hämtning från Confluence: %v", err)
+		log.Printf("FEL vid hämtning av spaces: %v", err)
 		return
 	}
 
 	userCache := make(map[string]UserResponse)
 	var newActivitiesCount int
 
-	for _, content := range pageResponse.Results {
-		page := content
-
-		newActivitiesCount += syncPageActivities(client, repos, page, userCache)
-		newActivitiesCount += syncCommentActivities(client, repos, page, userCache)
+	for _, space := range spaces {
+		newActivitiesCount += syncSpace(client, repos, space, userCache)
 	}
 
-	log.Printf("Confluence-synkronisering slutförd. %d nya aktiviteter registrerades.", newActivitiesCount)
+	log.Printf("Confluence-synkronisering slutförd. %d nya aktiviteter registrerades i %d spaces.", newActivitiesCount, len(spaces))
 }
 
-// Hanterar “PAGE_CREATED” och “PAGE_UPDATED” activities
-func syncPageActivities(client *Client, repos Repositories, page Content, userCache map[string]UserResponse) int {
+// syncSpace hämtar allt innehåll i ett space som ändrats sedan spacets cursor och flyttar
+// sedan fram cursorn. Saknas cursor görs en full synk. Misslyckas något innehåll flyttas
+// cursorn bara fram till ändringarna före det, så att innehållet hämtas igen nästa gång.
+func syncSpace(client *Client, repos Repositories, space models.ConfluenceSpace, userCache map[string]UserResponse) int {
+	changed, err := client.SearchChangedContent(space.Key, space.SyncCursor)
+	if err != nil {
+		log.Printf("FEL vid hämtning från Confluence (space %s): %v", space.Key, err)
+		return 0
+	}
+
 	var newActivities int
+	var progress cursorProgress
+	syncedPages := make(map[string]bool)
+	failedPages := make(map[string]bool)
 
-	if page.Version.By.AccountID == "" {
-		return 0
+	// Sidor först, så att kommentarer på en sida som ändå synkas i sin helhet kan hoppas över
+	for _, content := range changed {
+		if content.Type == "comment" {
+			continue
+		}
+
+		page := content
+		if page.Space == nil {
+			page.Space = &Space{Key: space.Key}
+		}
+
+		n, err := syncPageActivities(client, repos, page, userCache)
+		newActivities += n
+		if err == nil {
+			n, err = syncCommentActivities(client, repos, page, userCache)
+			newActivities += n
+		}
+		if err != nil {
+			log.Printf("FEL vid synkronisering av sida %s (space %s): %v", page.ID, space.Key, err)
+			failedPages[page.ID] = true
+			progress.failed(content.Version.CreatedAt)
+			continue
+		}
+		syncedPages[page.ID] = true
+		progress.succeeded(content.Version.CreatedAt)
 	}
 
-	authorID := page.Version.By.AccountID
-	userDetails := getCachedUserDetails(client, authorID, userCache)
-	if userDetails == nil {
-		return 0
+	for _, comment := range changed {
+		if comment.Type != "comment" {
+			continue
+		}
+		if comment.Container == nil || syncedPages[comment.Container.ID] {
+			progress.succeeded(comment.Version.CreatedAt)
+			continue
+		}
+		// Sidan synkas om i sin helhet nästa gång, kommentaren med den
+		if failedPages[comment.Container.ID] {
+			progress.failed(comment.Version.CreatedAt)
+			continue
+		}
+
+		page := *comment.Container
+		if page.Space == nil {
+			page.Space = &Space{Key: space.Key}
+		}
+
+		n, err := syncComments(client, repos, page, []Content{comment}, userCache)
+		newActivities += n
+		if err != nil {
+			log.Printf("FEL vid synkronisering av kommentar %s (space %s): %v", comment.ID, space.Key, err)
+			progress.failed(comment.Version.CreatedAt)
+			continue
+		}
+		progress.succeeded(comment.Version.CreatedAt)
 	}
 
-	exists, err := repos.ActivityRepo.ActivityExists(page.ID, page.Version.Number)
-	if err != nil {
-		log.Printf("FEL vid kontroll av sidaktivitet: %v", err)
-		return 0
+	if cursor := progress.cursor(); !cursor.IsZero() {
+		if err := repos.SpaceRepo.UpdateSyncCursor(space.Key, cursor); err != nil {
+			log.Printf("FEL vid uppdatering av cursor för space %s: %v", space.Key, err)
+		}
 	}
-	if exists {
-		return 0
+
+	return newActivities
+}
+
+// cursorProgress håller reda på hur långt cursorn kan flyttas: till den senaste ändringen
+// som synkades, men inte förbi den tidigaste ändringen som misslyckades.
+type cursorProgress struct {
+	done         []time.Time
+	earliestFail time.Time
+}
+
+func (p *cursorProgress) succeeded(t time.Time) {
+	p.done = append(p.done, t)
+}
+
+func (p *cursorProgress) failed(t time.Time) {
+	if p.earliestFail.IsZero() || t.Before(p.earliestFail) {
+		p.earliestFail = t
 	}
+}
 
-	user, err := findOrCreateUser(authorID, userDetails.DisplayName, repos.UserRepo)
+// cursor är den nya cursorn, eller nollvärdet om den inte ska flyttas.
+func (p *cursorProgress) cursor() time.Time {
+	var latest time.Time
+	for _, t := range p.done {
+		if !p.earliestFail.IsZero() && !t.Before(p.earliestFail) {
+			continue
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// Hanterar “PAGE_CREATED” och “PAGE_UPDATED” activities.
+// Alla versioner från den senast registrerade fram till den nuvarande gås igenom, så att
+// varje sparning krediteras sin egen författare och diffas mot versionen innan.
+// För sidor utan registrerad aktivitet krediteras bara den nuvarande versionen, historiken
+// läses in med ett backfill-jobb. Vid fel returneras de aktiviteter som hann registreras
+// tillsammans med felet.
+func syncPageActivities(client *Client, repos Repositories, page Content, userCache map[string]UserResponse) (int, error) {
+	var newActivities int
+
+	lastVersion, err := repos.ActivityRepo.GetLatestPageVersion(page.ID)
 	if err != nil {
-		log.Printf("FEL vid hantering av sid-användare %s: %v", userDetails.DisplayName, err)
-		return 0
+		return 0, fmt.Errorf("kunde inte kontrollera sidaktivitet: %w", err)
+	}
+	if lastVersion >= page.Version.Number {
+		return 0, nil
 	}
-	// Skapa user_stats om den inte finns
-	if err := repos.UserStatsRepo.CreateStatsForUser(user.ID); err != nil {
-		log.Printf("Kunde inte skapa user_stats för user %d: %v", user.ID, err)
+	if lastVersion == 0 {
+		lastVersion = page.Version.Number - 1
 	}
 
-	var activityType string
-	var pointsAwarded int
-	if page.Version.Number == 1 {
-		activityType = "PAGE_CREATED"
-		pointsAwarded = PointsForPageCreated()
-		// Uppdatera user_stats för nya sidor
-		if err := repos.UserStatsRepo.UpdateUserStatsCreatedPages(user.ID); err != nil {
-			log.Printf("Kunde inte uppdatera created pages för user %d: %v", user.ID, err)
+	var previousContent string
+	if lastVersion > 0 {
+		previousContent, err = client.GetPageVersionContent(page.ID, lastVersion)
+		if err != nil {
+			return 0, fmt.Errorf("kunde inte hämta gammal version (%d): %w", lastVersion, err)
 		}
-	} else {
-		activityType = "PAGE_UPDATED"
+	}
 
-		oldVersion := page.Version.Number - 1
-		oldContent, err := client.GetPageVersionContent(page.ID, oldVersion)
+	for number := lastVersion + 1; number <= page.Version.Number; number++ {
+		pageVersion, err := client.GetPageVersion(page.ID, number)
 		if err != nil {
-			log.Printf("FEL: Kunde inte hämta gammal version (%d) för sida %s: %v", oldVersion, page.ID, err)
-			pointsAwarded = 0
+			// Avbryt här. Cursorn flyttas inte förbi sidan, så nästa synk fortsätter
+			// från den senast registrerade versionen
+			return newActivities, fmt.Errorf("kunde inte hämta version %d: %w", number, err)
 		}
-		log.Printf("OLD version %d content length: %d", oldVersion, len(oldContent))
 
-		newContent, err := client.GetPageVersionContent(page.ID, page.Version.Number)
+		created, err := syncPageVersion(client, repos, page, pageVersion.Version, previousContent, pageVersion.Content, userCache)
 		if err != nil {
-			log.Printf("FEL: Kunde inte hämta ny version (%d) för sida %s: %v", page.Version.Number, page.ID, err)
-			pointsAwarded = 0
+			return newActivities, fmt.Errorf("kunde inte registrera version %d: %w", number, err)
 		}
-
-		if err == nil {
-			pointsAwarded = PointsForPageUpdated(oldContent, newContent)
-			log.Printf("Poäng utdelade: %d (diff mellan version %d → %d)", pointsAwarded, oldVersion, page.Version.Number)
-			// Uppdatera user_stats för redigerade sidor
-			if err := repos.UserStatsRepo.UpdateUserStatsEditedPages(user.ID); err != nil {
-				log.Printf("Kunde inte uppdatera edited pages för user %d: %v", user.ID, err)
-			}
+		if created {
+			newActivities++
 		}
-		log.Printf("NEW version %d content length: %d", page.Version.Number, len(newContent))
+		previousContent = pageVersion.Content
+	}
+
+	return newActivities, nil
+}
+
+// syncPageVersion registrerar en aktivitet för en enskild sidversion. Versioner utan
+// författare (t.ex. sparade av en app) hoppas över.
+func syncPageVersion(client *Client, repos Repositories, page Content, version Version, oldContent, newContent string, userCache map[string]UserResponse) (bool, error) {
+	if version.By.AccountID == "" {
+		return false, nil
+	}
 
+	exists, err := repos.ActivityRepo.ActivityExists(page.ID, version.Number)
+	if err != nil {
+		return false, err
 	}
-	log.Printf("Sida: %s av %s (%s)", page.Title, user.DisplayName, activityType)
-
-	activity := models.Activity{
-		UserID:                  user.ID,
-		ConfluencePageID:        page.ID,
-		ConfluenceVersionNumber: page.Version.Number,
-		ActivityType:            activityType,
-		PointsAwarded:           pointsAwarded,
+	if exists {
+		return false, nil
 	}
 
-	if _, err := repos.ActivityRepo.CreateActivity(&activity); err == nil {
-		err := repos.UserRepo.UpdateUserPoints(user.ID, pointsAwarded)
-		if err != nil {
-			return 0
-		}
-		newActivities++
+	authorID := version.By.AccountID
+	userDetails := getCachedUserDetails(client, authorID, userCache)
+	if userDetails == nil {
+		return false, fmt.Errorf("kunde inte hämta användardetaljer för %s", authorID)
 	}
 
-	return newActivities
+	activityType := "PAGE_CREATED"
+	var changes *models.DiffBreakdown
+	if version.Number > 1 {
+		activityType = "PAGE_UPDATED"
+		changes = scoring.AnalyzeStorage(oldContent, newContent)
+	}
+
+	activity, err := repos.Recorder.Record(integrations.Event{
+		Source:      integrations.SourceConfluence,
+		ContentID:   page.ID,
+		Version:     version.Number,
+		Type:        activityType,
+		Actor:       integrations.Actor{ID: authorID, DisplayName: userDetails.DisplayName},
+		OccurredAt:  version.CreatedAt,
+		Changes:     changes,
+		ContentHash: scoring.ContentHash(newContent),
+		SpaceKey:    spaceKey(page),
+		PagePath:    pagePath(page),
+	})
+	if err != nil || activity == nil {
+		return false, err
+	}
+	log.Printf("Sida: %s av %s (%s, version %d), poäng: %d", page.Title, userDetails.DisplayName, activityType, version.Number, activity.PointsAwarded)
+
+	return true, nil
 }
 
-func syncCommentActivities(client *Client, repos Repositories, page Content, userCache map[string]UserResponse) int {
-	var newActivities int
+func syncCommentActivities(client *Client, repos Repositories, page Content, userCache map[string]UserResponse) (int, error) {
+	comments, err := client.GetAllComments(page)
+	if err != nil {
+		return 0, fmt.Errorf("kunde inte hämta kommentarer: %w", err)
+	}
 
-	if page.Children.Comment == nil {
-		return 0
+	return syncComments(client, repos, page, comments, userCache)
+}
+
+// syncComments registrerar aktiviteter för de givna kommentarerna på en sida. En kommentar
+// som misslyckas hindrar inte de andra, men det första felet returneras så att
+// kommentarerna hämtas igen. Redan registrerade kommentarer hoppas över då.
+func syncComments(client *Client, repos Repositories, page Content, comments []Content, userCache map[string]UserResponse) (int, error) {
+	var newActivities int
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
 
-	for _, comment := range page.Children.Comment.Results {
+	for _, comment := range comments {
 		if comment.Type != "comment" {
 			continue
 		}
@@ -148,12 +284,20 @@ func syncCommentActivities(client *Client, repos Repositories, page Content, use
 				continue
 			}
 			log.Printf("Varning: Kunde inte hämta detaljer för kommentar %s: %v", comment.ID, err)
+			fail(err)
 			continue
 		}
 
 		var activityType string
-		var points int
 		var ownerID, ownerName string
+		var text, author string
+		occurredAt := fullComment.Version.CreatedAt
+		if fullComment.Body != nil {
+			text = scoring.VisibleText(fullComment.Body.Storage.Value)
+		}
+		if fullComment.History != nil {
+			author = fullComment.History.CreatedBy.AccountID
+		}
 
 		isResolved := fullComment.Extensions != nil &&
 			fullComment.Extensions.Resolution != nil &&
@@ -161,17 +305,22 @@ func syncCommentActivities(client *Client, repos Repositories, page Content, use
 
 		if isResolved {
 			resHistory, err := client.GetCommentResolutionHistory(fullComment.ID)
-			if err != nil || !resHistory.Found {
+			if err != nil {
+				fail(err)
+				continue
+			}
+			if !resHistory.Found {
 				continue
 			}
 			ownerID = resHistory.AccountID
 			userDetails := getCachedUserDetails(client, ownerID, userCache)
 			if userDetails == nil {
+				fail(fmt.Errorf("kunde inte hämta användardetaljer för %s", ownerID))
 				continue
 			}
 			ownerName = userDetails.DisplayName
+			occurredAt = resHistory.ResolvedAt
 			activityType = "RESOLVED_COMMENT"
-			points = PointsForResolvedComment()
 
 			// Lägg till offset på versionen för att separera från COMMENT_CREATED
 			fullComment.Version.Number += 100000
@@ -181,65 +330,67 @@ func syncCommentActivities(client *Client, repos Repositories, page Content, use
 			ownerID = fullComment.Version.By.AccountID
 			userDetails := getCachedUserDetails(client, ownerID, userCache)
 			if userDetails == nil {
+				fail(fmt.Errorf("kunde inte hämta användardetaljer för %s", ownerID))
 				continue
 			}
 			ownerName = userDetails.DisplayName
 			activityType = "COMMENT_CREATED"
-			points = PointsForCommentCreated()
 
 		} else {
 			continue
 		}
 
-		// Hitta eller skapa användare
-		user, err := findOrCreateUser(ownerID, ownerName, repos.UserRepo)
+		// Kontrollera om aktiviteten redan finns (unik på CommentID + ActivityType)
+		exists, err := repos.ActivityRepo.ActivityExistsWithType(fullComment.ID, activityType)
 		if err != nil {
+			fail(err)
 			continue
 		}
-
-		// Skapa user_stats om den inte finns
-		if err := repos.UserStatsRepo.CreateStatsForUser(user.ID); err != nil {
-			log.Printf("Kunde inte skapa user_stats för user %d: %v", user.ID, err)
+		if exists {
+			continue
 		}
 
-		// Kontrollera om aktiviteten redan finns (unik på CommentID + ActivityType)
-		exists, err := repos.ActivityRepo.ActivityExistsWithType(fullComment.ID, activityType)
-		if err != nil || exists {
+		activity, err := repos.Recorder.Record(integrations.Event{
+			Source:       integrations.SourceConfluence,
+			ContentID:    fullComment.ID,
+			Version:      fullComment.Version.Number,
+			Type:         activityType,
+			Actor:        integrations.Actor{ID: ownerID, DisplayName: ownerName},
+			OccurredAt:   occurredAt,
+			Text:         text,
+			ContentOwner: author,
+			SpaceKey:     spaceKey(page),
+			PagePath:     pagePath(page),
+		})
+		if err != nil {
+			log.Printf("FEL vid registrering av kommentar %s: %v", fullComment.ID, err)
+			fail(err)
 			continue
 		}
-
-		// Skapa aktivitet
-		activity := models.Activity{
-			UserID:                  user.ID,
-			ConfluencePageID:        fullComment.ID,
-			ConfluenceVersionNumber: fullComment.Version.Number,
-			ActivityType:            activityType,
-			PointsAwarded:           points,
+		if activity != nil {
+			newActivities++
+			log.Printf("Kommentar: %s av %s (%s), poäng: %d", page.Title, ownerName, activityType, activity.PointsAwarded)
 		}
+	}
 
-		if _, err := repos.ActivityRepo.CreateActivity(&activity); err == nil {
-			// Uppdatera poäng direkt
-			if err := repos.UserRepo.UpdateUserPoints(user.ID, points); err != nil {
-				continue
-			}
-
-			// Uppdatera user_stats direkt beroende på aktivitetstyp
-			if activityType == "COMMENT_CREATED" {
-				if err := repos.UserStatsRepo.UpdateUserStatsComments(user.ID); err != nil {
-					log.Printf("Kunde inte uppdatera comments för user %d: %v", user.ID, err)
-				}
-			} else if activityType == "RESOLVED_COMMENT" {
-				if err := repos.UserStatsRepo.UpdateUserStatsResolvedComments(user.ID); err != nil {
-					log.Printf("Kunde inte uppdatera resolved_comments för user %d: %v", user.ID, err)
-				}
-			}
+	return newActivities, firstErr
+}
 
-			newActivities++
-			log.Printf("Kommentar: %s av %s (%s), poäng: %d", page.Title, user.DisplayName, activityType, points)
-		}
+// spaceKey returnerar nyckeln för det space som sidan ligger i.
+func spaceKey(page Content) string {
+	if page.Space == nil {
+		return ""
 	}
+	return page.Space.Key
+}
 
-	return newActivities
+// pagePath returnerar sidans förfäder följt av sidan själv, från roten och nedåt.
+func pagePath(page Content) []string {
+	path := make([]string, 0, len(page.Ancestors)+1)
+	for _, ancestor := range page.Ancestors {
+		path = append(path, ancestor.ID)
+	}
+	return append(path, page.ID)
 }
 
 // Hjälpfunktion för caching
@@ -257,30 +408,3 @@ func getCachedUserDetails(client *Client, accountID string, cache map[string]Use
 	cache[accountID] = *details
 	return details
 }
-
-// findOrCreateUser letar efter en användare med ett Confluence-ID och skapar den om den inte finns.
-func findOrCreateUser(authorID, authorName string, userRepo *database.UserRepository) (*models.User, error) {
-	user, err := userRepo.GetUserByConfluenceID(authorID)
-	if err != nil {
-		return nil, fmt.Errorf("databasfel vid sökning efter användare: %w", err)
-	}
-
-	if user != nil {
-		return user, nil
-	}
-
-	log.Printf("Ny användare upptäckt: %s. Skapar profil...", authorName)
-	newUser := models.User{
-		ConfluenceAuthorID: authorID,
-		DisplayName:        authorName,
-		AvatarURL:          sql.NullString{String: "", Valid: false},
-	}
-
-	newID, err := userRepo.CreateUser(&newUser)
-	if err != nil {
-		return nil, fmt.Errorf("kunde inte skapa ny användare: %w", err)
-	}
-	newUser.ID = newID
-
-	return &newUser, nil
-}
\ No newline at end of file
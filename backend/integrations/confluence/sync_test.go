@@ -0,0 +1,40 @@
+package confluence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorProgress(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2024, 1, 10, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		succeeded []time.Time
+		failed    []time.Time
+		want      time.Time
+	}{
+		{"inget synkat", nil, nil, time.Time{}},
+		{"senaste lyckade", []time.Time{at(3), at(5), at(4)}, nil, at(5)},
+		{"stannar före det tidigaste felet", []time.Time{at(1), at(2), at(4), at(6)}, []time.Time{at(5), at(3)}, at(2)},
+		{"samma tid som ett fel räknas inte", []time.Time{at(1), at(3)}, []time.Time{at(3)}, at(1)},
+		{"första ändringen misslyckades", []time.Time{at(2), at(3)}, []time.Time{at(1)}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p cursorProgress
+			for _, s := range tt.succeeded {
+				p.succeeded(s)
+			}
+			for _, f := range tt.failed {
+				p.failed(f)
+			}
+			if got := p.cursor(); !got.Equal(tt.want) {
+				t.Errorf("cursor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package confluence
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxWebhookBody är den största payload vi tar emot från Confluence.
+const maxWebhookBody = 1 << 20
+
+// webhookQueueSize är hur många event som får vänta på behandling. Är kön full svarar vi 503
+// och låter Confluence försöka igen, pollingen tar dessutom det som tappas bort.
+const webhookQueueSize = 100
+
+// WebhookEvent är de delar av en Confluence-webhook som vi behöver. Innehållet hämtas
+// alltid på nytt från API:et, så payloaden används bara för att veta vad som ändrats.
+type WebhookEvent struct {
+	Event   string          `json:"event"`
+	Page    *WebhookContent `json:"page,omitempty"`
+	Blog    *WebhookContent `json:"blog,omitempty"`
+	Comment *WebhookContent `json:"comment,omitempty"`
+}
+
+// WebhookContent är sidan, blogginlägget eller kommentaren som ett event gäller.
+type WebhookContent struct {
+	ID       WebhookID `json:"id"`
+	SpaceKey string    `json:"spaceKey"`
+}
+
+// WebhookID tar emot ID:n både som tal och som strängar, Confluence skickar olika i olika event.
+type WebhookID string
+
+func (id *WebhookID) UnmarshalJSON(data []byte) error {
+	*id = WebhookID(strings.Trim(string(data), `"`))
+	return nil
+}
+
+// WebhookHandler tar emot webhooks från Confluence på POST /api/v1/webhooks/confluence.
+// Anropen måste vara signerade med X-Hub-Signature: sha256=<HMAC-SHA256 av bodyn med Secret>.
+// Eventen läggs i en kö och behandlas ett i taget av en worker.
+type WebhookHandler struct {
+	Service *Service
+	Secret  string
+
+	queue chan WebhookEvent
+}
+
+// NewWebhookHandler skapar en WebhookHandler och startar workern som behandlar kön.
+func NewWebhookHandler(service *Service, secret string) *WebhookHandler {
+	h := &WebhookHandler{
+		Service: service,
+		Secret:  secret,
+		queue:   make(chan WebhookEvent, webhookQueueSize),
+	}
+	go func() {
+		for event := range h.queue {
+			h.Service.HandleWebhookEvent(event)
+		}
+	}()
+	return h
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Secret == "" {
+		http.Error(w, "Confluence webhooks are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(h.Secret, body, r.Header.Get("X-Hub-Signature")) {
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Svara direkt, Confluence väntar inte länge och behandlingen kan ta tid
+	select {
+	case h.queue <- event:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		log.Printf("Webhook-kön är full, %s tappas", event.Event)
+		http.Error(w, "Webhook queue is full", http.StatusServiceUnavailable)
+	}
+}
+
+// validSignature jämför signaturen i headern med HMAC-SHA256 av bodyn.
+func validSignature(secret string, body []byte, header string) bool {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// HandleWebhookEvent registrerar aktiviteterna för ett webhook-event med samma logik som
+// SyncActivities. Event som inte ger poäng (t.ex. borttagningar) ignoreras.
+func (s *Service) HandleWebhookEvent(event WebhookEvent) {
+	var contentID string
+	switch {
+	case event.Event == "comment_removed":
+		return
+	case strings.HasPrefix(event.Event, "comment_") && event.Comment != nil:
+		// comment_created, comment_updated och resolutioner av inline-kommentarer
+		contentID = string(event.Comment.ID)
+	case (event.Event == "page_created" || event.Event == "page_updated") && event.Page != nil:
+		contentID = string(event.Page.ID)
+	case (event.Event == "blog_created" || event.Event == "blog_updated") && event.Blog != nil:
+		contentID = string(event.Blog.ID)
+	}
+	if contentID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := s.Client.GetContent(contentID)
+	if err != nil {
+		log.Printf("FEL vid hämtning av innehåll %s för webhook %s: %v", contentID, event.Event, err)
+		return
+	}
+
+	page := *content
+	if content.Type == "comment" {
+		if content.Container == nil {
+			return
+		}
+		page = *content.Container
+		if page.Space == nil {
+			page.Space = content.Space
+		}
+	}
+
+	// Bara spaces som synkas ska ge poäng
+	if page.Space == nil {
+		return
+	}
+	enabled, err := s.Repositories.SpaceRepo.IsSpaceEnabled(page.Space.Key)
+	if err != nil || !enabled {
+		return
+	}
+
+	userCache := make(map[string]UserResponse)
+	var newActivities int
+	if content.Type == "comment" {
+		newActivities, err = syncComments(s.Client, s.Repositories, page, []Content{*content}, userCache)
+	} else {
+		newActivities, err = syncPageActivities(s.Client, s.Repositories, page, userCache)
+	}
+	if err != nil {
+		// Pollningen hämtar innehållet igen, cursorn flyttas inte förbi det förrän det har lyckats
+		log.Printf("FEL vid webhook %s för %s: %v", event.Event, contentID, err)
+	}
+
+	log.Printf("Webhook %s för %s: %d nya aktiviteter.", event.Event, contentID, newActivities)
+}
@@ -0,0 +1,40 @@
+package integrations
+
+import (
+	"gamification-api/backend/models"
+	"time"
+)
+
+// SourceConfluence är källnamnet för Confluence.
+const SourceConfluence = "confluence"
+
+// Actor är den som utförde en händelse, identifierad med källans eget användar-ID.
+type Actor struct {
+	ID          string
+	DisplayName string
+}
+
+// Event är en poänggivande händelse från en aktivitetskälla, oberoende av vilken källa det är.
+// Recorder gör om den till en models.Activity.
+type Event struct {
+	// Source är namnet på källan, t.ex. "confluence" eller "git"
+	Source string
+	// ContentID och Version identifierar händelsen i källan (sida/version, fil/commit osv.)
+	ContentID  string
+	Version    int
+	Type       string
+	Actor      Actor
+	OccurredAt time.Time
+	// Changes är vad som ändrades för typer som poängsätts efter storlek, annars nil.
+	// Poängen räknas ut av Recorder med den aktiva poängregeln.
+	Changes *models.DiffBreakdown
+	// Text är innehållets synliga text, används för kommentarer
+	Text string
+	// ContentHash är en hash av innehållets synliga text efter ändringen. Tom om källan inte har någon.
+	ContentHash string
+	// ContentOwner är källans ID för den som skrev innehållet, t.ex. kommentaren som resolvades
+	ContentOwner string
+	// SpaceKey och PagePath används av tävlingsregler och filter. Tomma om källan saknar motsvarighet.
+	SpaceKey string
+	PagePath []string
+}
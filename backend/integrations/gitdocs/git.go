@@ -0,0 +1,149 @@
+package gitdocs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// commit är en commit med de filer som ändrades i den.
+type commit struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	AuthoredAt  time.Time
+	Changes     []fileChange
+}
+
+// fileChange är en tillagd, ändrad eller flyttad fil. Borttagna filer ger inga poäng och tas inte med.
+type fileChange struct {
+	Path    string
+	OldPath string
+	Added   bool
+}
+
+// Separatorer i git log-formatet: \x1e mellan commits och \x1f mellan fält. Med -z separeras
+// statusrader och sökvägar med NUL, så att filnamn med tab, radbrytning eller å/ä/ö kommer med som de är.
+const (
+	recordSeparator = "\x1e"
+	fieldSeparator  = "\x1f"
+)
+
+// commitsSince hämtar commits efter since (en commit-hash), äldst först.
+// Är since tom hämtas hela historiken. Har since försvunnit ur historiken, t.ex. efter en
+// force-push, läses hela historiken om. Redan registrerade ändringar räknas inte dubbelt.
+func commitsSince(repo, since string) ([]commit, error) {
+	revision := "HEAD"
+	if since != "" {
+		reachable, err := isAncestor(repo, since)
+		if err != nil {
+			return nil, err
+		}
+		if reachable {
+			revision = since + "..HEAD"
+		} else {
+			log.Printf("Commit %s finns inte längre i historiken för %s, läser om hela historiken.", since, repo)
+		}
+	}
+
+	out, err := git(repo, "log", "--reverse", "--no-merges", "--find-renames", "--name-status", "-z",
+		"--format="+recordSeparator+"%H"+fieldSeparator+"%an"+fieldSeparator+"%ae"+fieldSeparator+"%aI",
+		revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLog(out)
+}
+
+// isAncestor kollar om commiten finns och går att nå från HEAD. git merge-base avslutar
+// med 1 om den inte är en förfader och 128 om commiten inte finns alls.
+func isAncestor(repo, hash string) (bool, error) {
+	_, err := git(repo, "merge-base", "--is-ancestor", hash, "HEAD")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 128) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// parseLog tolkar utdata från git log med formatet i commitsSince. Varje commit är
+// huvudraden följd av NUL-separerade fält: status, sökväg och för flyttar även den nya sökvägen.
+func parseLog(out string) ([]commit, error) {
+	var commits []commit
+
+	for _, record := range strings.Split(out, recordSeparator) {
+		fields := strings.Split(record, "\x00")
+		header := strings.TrimSpace(fields[0])
+		if header == "" {
+			continue
+		}
+
+		headerFields := strings.Split(header, fieldSeparator)
+		if len(headerFields) != 4 {
+			return nil, fmt.Errorf("oväntat format från git log: %q", header)
+		}
+		authoredAt, err := time.Parse(time.RFC3339, headerFields[3])
+		if err != nil {
+			return nil, err
+		}
+
+		c := commit{Hash: headerFields[0], AuthorName: headerFields[1], AuthorEmail: headerFields[2], AuthoredAt: authoredAt}
+		rest := fields[1:]
+		for len(rest) > 0 {
+			// Statusen står efter radbrytningen som avslutar huvudraden
+			status := strings.TrimSpace(rest[0])
+			rest = rest[1:]
+			if status == "" {
+				continue
+			}
+
+			paths := 1
+			if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+				paths = 2
+			}
+			if len(rest) < paths {
+				return nil, fmt.Errorf("oväntat format från git log för commit %s: status %q saknar sökväg", c.Hash, status)
+			}
+			oldPath, path := rest[0], rest[paths-1]
+			rest = rest[paths:]
+
+			switch {
+			case status == "A":
+				c.Changes = append(c.Changes, fileChange{Path: path, OldPath: path, Added: true})
+			case status == "M":
+				c.Changes = append(c.Changes, fileChange{Path: path, OldPath: path})
+			case status == "R100":
+				// Ren flytt utan ändrat innehåll ger inga poäng
+			case strings.HasPrefix(status, "R"):
+				c.Changes = append(c.Changes, fileChange{Path: path, OldPath: oldPath})
+			}
+		}
+
+		commits = append(commits, c)
+	}
+
+	return commits, nil
+}
+
+// fileAt hämtar innehållet i en fil vid en viss revision.
+func fileAt(repo, revision, path string) (string, error) {
+	return git(repo, "show", revision+":"+path)
+}
+
+func git(repo string, args ...string) (string, error) {
+	// core.quotePath=false så att sökvägar med å/ä/ö inte escapas
+	cmd := exec.Command("git", append([]string{"-C", repo, "-c", "core.quotePath=false"}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
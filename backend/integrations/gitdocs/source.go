@@ -0,0 +1,142 @@
+// Package gitdocs är en aktivitetskälla för dokumentation i Git-repon. Den läser commit-historiken
+// och ger poäng för nya och ändrade Markdown- och AsciiDoc-filer.
+package gitdocs
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"gamification-api/backend/database"
+	"gamification-api/backend/integrations"
+	"gamification-api/backend/models"
+	"gamification-api/backend/scoring"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// SourceName är källnamnet för Git-dokumentation.
+const SourceName = "git"
+
+// docExtensions är de filtyper som räknas som dokumentation.
+var docExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".adoc":     true,
+	".asciidoc": true,
+}
+
+// Source läser ett eller flera lokala (eller bare) Git-repon. Hur långt varje repo har lästs
+// sparas som senast behandlade commit i source_cursors.
+type Source struct {
+	Repos      []string
+	Recorder   *integrations.Recorder
+	CursorRepo *database.SourceCursorRepository
+}
+
+// Name implementerar integrations.ActivitySource.
+func (s *Source) Name() string {
+	return SourceName
+}
+
+// Sync implementerar integrations.ActivitySource och läser nya commits i alla repon.
+func (s *Source) Sync() error {
+	var newActivities int
+	for _, repo := range s.Repos {
+		n, err := s.syncRepo(repo)
+		newActivities += n
+		if err != nil {
+			log.Printf("FEL vid synkronisering av Git-repo %s: %v", repo, err)
+		}
+	}
+
+	log.Printf("Git-synkronisering slutförd. %d nya aktiviteter registrerades i %d repon.", newActivities, len(s.Repos))
+	return nil
+}
+
+func (s *Source) syncRepo(repo string) (int, error) {
+	cursor, err := s.CursorRepo.GetCursor(SourceName, repo)
+	if err != nil {
+		return 0, err
+	}
+
+	commits, err := commitsSince(repo, cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	var newActivities int
+	for _, c := range commits {
+		for _, change := range c.Changes {
+			if !docExtensions[strings.ToLower(filepath.Ext(change.Path))] {
+				continue
+			}
+
+			created, err := s.recordChange(repo, c, change)
+			if err != nil {
+				// Cursorn flyttas inte förbi commiten, så den försöks igen nästa gång
+				return newActivities, fmt.Errorf("commit %s, fil %s: %w", c.Hash, change.Path, err)
+			}
+			if created {
+				newActivities++
+			}
+		}
+
+		if err := s.CursorRepo.SetCursor(SourceName, repo, c.Hash); err != nil {
+			return newActivities, err
+		}
+	}
+
+	return newActivities, nil
+}
+
+func (s *Source) recordChange(repo string, c commit, change fileChange) (bool, error) {
+	newContent, err := fileAt(repo, c.Hash, change.Path)
+	if err != nil {
+		return false, err
+	}
+
+	activityType := models.ActivityTypeDocCreated
+	var changes *models.DiffBreakdown
+	if !change.Added {
+		oldContent, err := fileAt(repo, c.Hash+"^", change.OldPath)
+		if err != nil {
+			return false, err
+		}
+		activityType = models.ActivityTypeDocUpdated
+		changes = scoring.AnalyzeText(oldContent, newContent)
+	}
+
+	activity, err := s.Recorder.Record(integrations.Event{
+		Source:     SourceName,
+		ContentID:  contentID(c.Hash, change.Path),
+		Version:    1,
+		Type:       activityType,
+		Actor:      integrations.Actor{ID: strings.ToLower(c.AuthorEmail), DisplayName: c.AuthorName},
+		OccurredAt: c.AuthoredAt,
+		Changes:    changes,
+		SpaceKey:   repoName(repo),
+	})
+	if err != nil || activity == nil {
+		return false, err
+	}
+
+	log.Printf("Dokument: %s i %s av %s (%s), poäng: %d", change.Path, repoName(repo), c.AuthorName, activityType, activity.PointsAwarded)
+	return true, nil
+}
+
+// contentID identifierar en filändring i en commit. Långa sökvägar hashas så att ID:t får plats i databasen.
+func contentID(hash, path string) string {
+	id := hash + ":" + path
+	if len(id) <= 255 {
+		return id
+	}
+	sum := sha1.Sum([]byte(path))
+	return hash + ":" + hex.EncodeToString(sum[:])
+}
+
+// repoName är repots katalognamn utan .git, t.ex. "handbook" för /srv/git/handbook.git.
+func repoName(repo string) string {
+	return strings.TrimSuffix(filepath.Base(filepath.Clean(repo)), ".git")
+}
@@ -0,0 +1,59 @@
+package integrations
+
+import (
+	"gamification-api/backend/config"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// Guard är missbruksskyddet. Den letar efter mönster som tyder på poängfarmning, t.ex. att
+// ändra blanksteg fram och tillbaka, skriva "+1"-kommentarer eller resolva sina egna kommentarer.
+// Flaggade aktiviteter registreras utan poäng och hamnar i modereringskön.
+type Guard struct {
+	Repo   *database.ModerationRepository
+	Config config.ModerationConfig
+}
+
+// Check returnerar skälen till att händelsen ska flaggas (tom om den ser ärlig ut) och tidigare
+// aktiviteter som den avslöjar, t.ex. ändringarna som en återställning tar bort.
+func (g *Guard) Check(e Event, userID int64) (reasons []string, related []int64) {
+	switch e.Type {
+	case models.ActivityTypePageUpdated, models.ActivityTypeDocUpdated:
+		if g.Config.MinDiffSize > 0 && e.Changes != nil && e.Changes.Size < g.Config.MinDiffSize {
+			reasons = append(reasons, models.FlagReasonTrivialEdit)
+		}
+	case models.ActivityTypeCommentCreated:
+		if g.Config.MinCommentWords > 0 && len(strings.Fields(e.Text)) < g.Config.MinCommentWords {
+			reasons = append(reasons, models.FlagReasonTrivialComment)
+		}
+	case models.ActivityTypeResolvedComment:
+		if e.ContentOwner != "" && e.ContentOwner == e.Actor.ID {
+			reasons = append(reasons, models.FlagReasonSelfResolution)
+		}
+	}
+
+	if g.Config.MaxActivitiesPerHour > 0 {
+		count, err := g.Repo.CountActivitiesSince(userID, e.OccurredAt.Add(-time.Hour), e.OccurredAt)
+		if err != nil {
+			log.Printf("FEL vid kontroll av aktivitetstakt för user %d: %v", userID, err)
+		} else if count >= g.Config.MaxActivitiesPerHour {
+			reasons = append(reasons, models.FlagReasonRateLimit)
+		}
+	}
+
+	// En återställning räknas bara som farmning om samma användare gjorde ändringarna som återställs
+	if e.ContentHash != "" && e.Type == models.ActivityTypePageUpdated {
+		found, edits, err := g.Repo.FindRevertedEdits(e.ContentID, e.ContentHash, e.Version, userID)
+		if err != nil {
+			log.Printf("FEL vid kontroll av återställning för %s: %v", e.ContentID, err)
+		} else if found && len(edits) > 0 {
+			reasons = append(reasons, models.FlagReasonRevert)
+			related = edits
+		}
+	}
+
+	return reasons, related
+}
@@ -0,0 +1,184 @@
+package integrations
+
+import (
+	"database/sql"
+	"fmt"
+	"gamification-api/backend/badges"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"gamification-api/backend/scoring"
+	"log"
+	"strings"
+)
+
+// Recorder gör om händelser från alla källor till aktiviteter och sköter det som är gemensamt:
+// att hitta eller skapa användaren, dubblettkontroll, poäng, user_stats och badges.
+type Recorder struct {
+	UserRepo      *database.UserRepository
+	ActivityRepo  *database.ActivityRepository
+	UserStatsRepo *database.UserStatsRepository
+	IdentityRepo  *database.IdentityRepository
+	Scoring       *scoring.Engine
+	Badges        *badges.Engine
+	// Guard flaggar misstänkt poängfarmning, nil stänger av kontrollerna
+	Guard *Guard
+}
+
+// statUpdates anger vilken räknare i user_stats varje aktivitetstyp påverkar.
+var statUpdates = map[string]func(*database.UserStatsRepository, int64) error{
+	models.ActivityTypePageCreated:     (*database.UserStatsRepository).UpdateUserStatsCreatedPages,
+	models.ActivityTypePageUpdated:     (*database.UserStatsRepository).UpdateUserStatsEditedPages,
+	models.ActivityTypeCommentCreated:  (*database.UserStatsRepository).UpdateUserStatsComments,
+	models.ActivityTypeResolvedComment: (*database.UserStatsRepository).UpdateUserStatsResolvedComments,
+	// Dokument i Git räknas som sidor
+	models.ActivityTypeDocCreated: (*database.UserStatsRepository).UpdateUserStatsCreatedPages,
+	models.ActivityTypeDocUpdated: (*database.UserStatsRepository).UpdateUserStatsEditedPages,
+}
+
+// Record registrerar en händelse. Den returnerar nil om händelsen redan var registrerad.
+func (r *Recorder) Record(e Event) (*models.Activity, error) {
+	if e.Actor.ID == "" {
+		return nil, fmt.Errorf("händelse %s för %s saknar användare", e.Type, e.ContentID)
+	}
+
+	exists, err := r.ActivityRepo.ActivityExistsWithVersion(e.ContentID, e.Version, e.Type)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	user, err := r.FindOrCreateUser(e.Source, e.Actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skapa user_stats om den inte finns
+	if err := r.UserStatsRepo.CreateStatsForUser(user.ID); err != nil {
+		log.Printf("Kunde inte skapa user_stats för user %d: %v", user.ID, err)
+	}
+
+	var diff int
+	if e.Changes != nil {
+		diff = e.Changes.Size
+	}
+	score := r.Scoring.Award(user.ID, e.Type, diff, e.OccurredAt)
+
+	var reasons []string
+	var related []int64
+	if r.Guard != nil {
+		reasons, related = r.Guard.Check(e, user.ID)
+	}
+	// Flaggade aktiviteter registreras utan poäng tills de har granskats
+	points := score.Points
+	if len(reasons) > 0 {
+		points = 0
+	}
+
+	activity := &models.Activity{
+		UserID:                  user.ID,
+		Source:                  e.Source,
+		ConfluencePageID:        e.ContentID,
+		ConfluenceVersionNumber: e.Version,
+		ActivityType:            e.Type,
+		PointsAwarded:           points,
+		RawPoints:               score.RawPoints,
+		DiffBreakdown:           e.Changes,
+		ContentHash:             e.ContentHash,
+		ScoringRuleID:           score.RuleID,
+		OccurredAt:              e.OccurredAt,
+		SpaceKey:                e.SpaceKey,
+		PagePath:                e.PagePath,
+	}
+
+	// Flaggade aktiviteter får ingen rad i liggaren förrän de har godkänts. Annars skrivs raden
+	// i samma transaktion som aktiviteten, så att poängen inte tappas om liggaren inte går att skriva
+	var entry *models.LedgerEntry
+	if len(reasons) == 0 {
+		entry = &models.LedgerEntry{
+			UserID:         user.ID,
+			EntryType:      models.LedgerEntryAward,
+			Points:         points,
+			LifetimePoints: points,
+			Reason:         e.Type,
+			OccurredAt:     activity.OccurredAt,
+		}
+	}
+	id, err := r.ActivityRepo.CreateActivity(activity, entry)
+	if err != nil {
+		return nil, err
+	}
+	activity.ID = id
+
+	for _, relatedID := range related {
+		relatedUserID, err := r.Guard.Repo.WithholdActivity(relatedID, models.FlagReasonRevert)
+		if err != nil {
+			log.Printf("Kunde inte flagga aktivitet %d: %v", relatedID, err)
+			continue
+		}
+		// Flaggade aktiviteter räknas inte i statistiken förrän de har godkänts
+		if err := r.UserStatsRepo.RebuildStatsForUser(relatedUserID); err != nil {
+			log.Printf("Kunde inte räkna om user_stats för user %d: %v", relatedUserID, err)
+		}
+	}
+
+	if len(reasons) > 0 {
+		log.Printf("Aktivitet %d (%s) av user %d flaggades: %s", id, e.Type, user.ID, strings.Join(reasons, ", "))
+		return activity, r.Guard.Repo.CreateFlag(id, reasons, score.Points)
+	}
+
+	if update, ok := statUpdates[e.Type]; ok {
+		if err := update(r.UserStatsRepo, user.ID); err != nil {
+			log.Printf("Kunde inte uppdatera user_stats (%s) för user %d: %v", e.Type, user.ID, err)
+		}
+	}
+	if _, err := r.Badges.CheckUser(user.ID); err != nil {
+		log.Printf("Kunde inte utvärdera badges för user %d: %v", user.ID, err)
+	}
+
+	return activity, nil
+}
+
+// FindOrCreateUser hittar användaren bakom ett ID i en källa och skapar den om den inte finns.
+// Confluence-användare hittas även via users.confluence_author_id.
+func (r *Recorder) FindOrCreateUser(source string, actor Actor) (*models.User, error) {
+	userID, err := r.IdentityRepo.GetUserIDByIdentity(source, actor.ID)
+	if err != nil {
+		return nil, fmt.Errorf("databasfel vid sökning efter identitet: %w", err)
+	}
+	if userID != 0 {
+		user, err := r.UserRepo.GetUserByID(userID)
+		if err != nil || user != nil {
+			return user, err
+		}
+	}
+
+	// Användare utanför Confluence får ett syntetiskt author-ID, t.ex. "git:anna@example.com"
+	authorID := actor.ID
+	if source != SourceConfluence {
+		authorID = source + ":" + actor.ID
+	}
+
+	user, err := r.UserRepo.GetUserByConfluenceID(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("databasfel vid sökning efter användare: %w", err)
+	}
+
+	if user == nil {
+		log.Printf("Ny användare upptäckt: %s (%s). Skapar profil...", actor.DisplayName, source)
+		user = &models.User{
+			ConfluenceAuthorID: authorID,
+			DisplayName:        actor.DisplayName,
+			AvatarURL:          sql.NullString{String: "", Valid: false},
+		}
+
+		newID, err := r.UserRepo.CreateUser(user)
+		if err != nil {
+			return nil, fmt.Errorf("kunde inte skapa ny användare: %w", err)
+		}
+		user.ID = newID
+	}
+
+	if err := r.IdentityRepo.LinkIdentity(source, actor.ID, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
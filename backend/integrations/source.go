@@ -0,0 +1,108 @@
+package integrations
+
+import (
+	"gamification-api/backend/config"
+	"log"
+	"sync"
+	"time"
+)
+
+// ActivitySource är en källa till poänggivande aktivitet, t.ex. Confluence eller ett Git-repo.
+// Sync hämtar det som ändrats sedan förra körningen och registrerar det via en Recorder.
+type ActivitySource interface {
+	Name() string
+	Sync() error
+}
+
+type registeredSource struct {
+	source ActivitySource
+	config config.SourceConfig
+	ticker *time.Ticker
+	stop   chan bool
+}
+
+// Registry håller alla aktivitetskällor och kör varje aktiverad källa med sitt eget intervall.
+type Registry struct {
+	mu      sync.Mutex
+	sources map[string]*registeredSource
+	order   []string
+}
+
+// NewRegistry skapar ett tomt register.
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]*registeredSource)}
+}
+
+// Register lägger till en källa. En källa med samma namn ersätts.
+func (r *Registry) Register(source ActivitySource, sourceConfig config.SourceConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sources[source.Name()]; !exists {
+		r.order = append(r.order, source.Name())
+	}
+	r.sources[source.Name()] = &registeredSource{source: source, config: sourceConfig, stop: make(chan bool)}
+}
+
+// Get hämtar en registrerad källa, eller nil om den inte finns.
+func (r *Registry) Get(name string) ActivitySource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rs, ok := r.sources[name]; ok {
+		return rs.source
+	}
+	return nil
+}
+
+// Start kör varje aktiverad källa direkt och sedan en gång per intervall.
+func (r *Registry) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.order {
+		rs := r.sources[name]
+		if !rs.config.Enabled {
+			log.Printf("Aktivitetskällan %s är avstängd.", name)
+			continue
+		}
+
+		log.Printf("Aktivitetskällan %s startad. Synkroniserar var %v.", name, rs.config.Interval)
+		rs.ticker = time.NewTicker(rs.config.Interval)
+		go run(rs)
+	}
+}
+
+func run(rs *registeredSource) {
+	syncSource(rs.source)
+
+	for {
+		select {
+		case <-rs.ticker.C:
+			syncSource(rs.source)
+		case <-rs.stop:
+			rs.ticker.Stop()
+			return
+		}
+	}
+}
+
+func syncSource(source ActivitySource) {
+	if err := source.Sync(); err != nil {
+		log.Printf("FEL vid synkronisering av %s: %v", source.Name(), err)
+	}
+}
+
+// Stop avslutar alla källor som har startats.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.order {
+		if rs := r.sources[name]; rs.ticker != nil {
+			log.Printf("Stoppar aktivitetskällan %s...", name)
+			rs.stop <- true
+			rs.ticker = nil
+		}
+	}
+}
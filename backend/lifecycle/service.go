@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"gamification-api/backend/database"
+	"log"
+	"time"
+)
+
+// Service kör periodiska jobb som hör till tävlingarnas och säsongernas livscykel,
+// t.ex. att arkivera slutresultatet när en tävling har tagit slut.
+type Service struct {
+	CompetitionRepo *database.CompetitionRepository
+	SeasonRepo      *database.SeasonRepository
+	ticker          *time.Ticker
+	stop            chan bool
+}
+
+// NewService skapar en ny livscykeltjänst.
+func NewService(competitionRepo *database.CompetitionRepository, seasonRepo *database.SeasonRepository) *Service {
+	return &Service{
+		CompetitionRepo: competitionRepo,
+		SeasonRepo:      seasonRepo,
+		stop:            make(chan bool),
+	}
+}
+
+// Start kör jobben direkt och sedan en gång per intervall.
+func (s *Service) Start(interval time.Duration) {
+	log.Printf("Livscykeltjänsten startad. Kontrollerar tävlingar och säsonger var %v.", interval)
+	s.ticker = time.NewTicker(interval)
+
+	go func() {
+		s.FinalizeEndedCompetitions()
+		s.RolloverSeasons()
+
+		for {
+			select {
+			case <-s.ticker.C:
+				s.FinalizeEndedCompetitions()
+				s.RolloverSeasons()
+			case <-s.stop:
+				s.ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop avslutar de periodiska jobben.
+func (s *Service) Stop() {
+	log.Println("Stoppar livscykeltjänsten...")
+	s.stop <- true
+}
+
+// FinalizeEndedCompetitions fryser resultatet för alla tävlingar vars slutdatum har passerat.
+func (s *Service) FinalizeEndedCompetitions() {
+	competitions, err := s.CompetitionRepo.GetCompetitionsToFinalize()
+	if err != nil {
+		log.Printf("FEL vid hämtning av avslutade tävlingar: %v", err)
+		return
+	}
+
+	for _, c := range competitions {
+		finalized, err := s.CompetitionRepo.FinalizeCompetition(&c)
+		if err != nil {
+			log.Printf("FEL: Kunde inte arkivera tävling %d (%s): %v", c.ID, c.Name, err)
+			continue
+		}
+		if finalized {
+			log.Printf("Tävling %d (%s) är avslutad och resultatet har arkiverats.", c.ID, c.Name)
+		}
+	}
+}
+
+// RolloverSeasons arkiverar säsonger som har tagit slut och nollställer poängen inför nästa.
+func (s *Service) RolloverSeasons() {
+	seasons, err := s.SeasonRepo.GetSeasonsToArchive()
+	if err != nil {
+		log.Printf("FEL vid hämtning av avslutade säsonger: %v", err)
+		return
+	}
+
+	for _, season := range seasons {
+		next, archived, err := s.SeasonRepo.ArchiveSeason(&season)
+		if err != nil {
+			log.Printf("FEL: Kunde inte arkivera säsong %d (%s): %v", season.ID, season.Name, err)
+			continue
+		}
+		if archived {
+			log.Printf("Säsong %d (%s) är avslutad och poängen har nollställts.", season.ID, season.Name)
+		}
+		if next != nil {
+			log.Printf("Säsong %d (%s) skapades och pågår till %s.", next.ID, next.Name, next.EndDate.Format(time.RFC3339))
+		}
+	}
+}
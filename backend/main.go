@@ -3,13 +3,20 @@ package main
 import (
 	"fmt"
 	"gamification-api/backend/auth" // Importera auth-paketet
+	"gamification-api/backend/badges"
 	"gamification-api/backend/config"
 	"gamification-api/backend/database"
+	"gamification-api/backend/integrations"
 	"gamification-api/backend/integrations/confluence"
+	"gamification-api/backend/integrations/gitdocs"
+	"gamification-api/backend/lifecycle"
 	"gamification-api/backend/router"
+	"gamification-api/backend/scoring"
 	"gamification-api/backend/seeder"
+	"gamification-api/backend/streaks"
 	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -31,20 +38,95 @@ func main() {
 	if err := seeder.SeedBadges(db); err != nil {
 		log.Fatalf("FATAL: Kunde inte seeda badges: %v", err)
 	}
+	if err := seeder.SeedScoringRules(db); err != nil {
+		log.Fatalf("FATAL: Kunde inte seeda poängregler: %v", err)
+	}
 
 	// Skapa repositories
 	userRepo := &database.UserRepository{DB: db}
 	activityRepo := &database.ActivityRepository{DB: db}
 	userStatsRepo := &database.UserStatsRepository{DB: db}
 	userBadgeRepo := &database.UserBadgeRepository{DB: db}
+	competitionRepo := &database.CompetitionRepository{DB: db}
+	spaceRepo := &database.SpaceRepository{DB: db}
+	backfillRepo := &database.BackfillRepository{DB: db}
+	identityRepo := &database.IdentityRepository{DB: db}
+	ledgerRepo := &database.LedgerRepository{DB: db}
+
+	// Poängsummorna ska alltid vara summan av poängliggaren
+	if err := ledgerRepo.RebuildTotals(); err != nil {
+		log.Printf("Varning: Kunde inte räkna om poängsummorna från poängliggaren: %v", err)
+	}
+
+	// Recorder registrerar aktiviteter från alla källor
+	recorder := &integrations.Recorder{
+		UserRepo:      userRepo,
+		ActivityRepo:  activityRepo,
+		UserStatsRepo: userStatsRepo,
+		IdentityRepo:  identityRepo,
+		Scoring: &scoring.Engine{
+			Repo:         &database.ScoringRuleRepository{DB: db},
+			ActivityRepo: activityRepo,
+			Caps:         config.LoadPointCapConfig(),
+		},
+		Badges: &badges.Engine{
+			BadgeRepo:     &database.BadgeRepository{DB: db},
+			UserBadgeRepo: userBadgeRepo,
+			MetricRepo:    &database.BadgeMetricRepository{DB: db},
+			UserRepo:      userRepo,
+			Streaks:       &streaks.Tracker{Repo: activityRepo, Config: config.LoadStreakConfig()},
+		},
+		Guard: &integrations.Guard{
+			Repo:   &database.ModerationRepository{DB: db},
+			Config: config.LoadModerationConfig(),
+		},
+	}
+
+	// Spaces från konfigurationen synkas alltid, fler kan läggas till via API:et
+	if err := spaceRepo.EnsureSpaces(cfg.ConfluenceSpaceKeys); err != nil {
+		log.Fatalf("FATAL: Kunde inte spara Confluence-spaces: %v", err)
+	}
 
-	// Skapa och starta Confluence-tjänsten
+	// Skapa Confluence-tjänsten
 	confluenceClient := confluence.NewClient(cfg.ConfluenceBaseURL, cfg.ConfluenceEmail, cfg.ConfluenceAPIToken)
-	confluenceService := confluence.NewService(confluenceClient, userRepo, activityRepo, userStatsRepo, userBadgeRepo)
-	confluenceService.Start(30 * time.Second) // Rimligt intervall för normal drift
+	confluenceService := confluence.NewService(confluenceClient, recorder, userRepo, activityRepo, userStatsRepo, userBadgeRepo, spaceRepo)
+	backfiller := confluence.NewBackfiller(confluenceService, backfillRepo)
+
+	// "backend backfill ..." kör ett backfill-jobb och avslutar, utan att starta servern
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err := runBackfillCommand(os.Args[2:], backfiller, backfillRepo); err != nil {
+			log.Fatalf("FATAL: Backfill misslyckades: %v", err)
+		}
+		return
+	}
+
+	// Med webhooks påslagna är pollingen bara en avstämning för event som har tappats bort
+	syncInterval := 30 * time.Second // Rimligt intervall för normal drift
+	if cfg.ConfluenceWebhookSecret != "" {
+		syncInterval = 10 * time.Minute
+	}
+
+	// Registrera och starta alla aktivitetskällor
+	sources := integrations.NewRegistry()
+	sources.Register(confluenceService, config.LoadSourceConfig(confluenceService.Name(), syncInterval))
+	if len(cfg.GitDocsRepos) > 0 {
+		gitSource := &gitdocs.Source{Repos: cfg.GitDocsRepos, Recorder: recorder, CursorRepo: &database.SourceCursorRepository{DB: db}}
+		sources.Register(gitSource, config.LoadSourceConfig(gitSource.Name(), 5*time.Minute))
+	}
+	sources.Start()
+
+	confluenceWebhook := confluence.NewWebhookHandler(confluenceService, cfg.ConfluenceWebhookSecret)
+
+	// Starta livscykeltjänsten som arkiverar avslutade tävlingar och säsonger
+	lifecycleService := lifecycle.NewService(competitionRepo, &database.SeasonRepository{DB: db})
+	lifecycleService.Start(1 * time.Minute)
+
+	// Räkna om alla badges schemalagt, så att ändrade villkor slår igenom
+	reevaluator := badges.NewReevaluator(recorder.Badges, &database.BadgeEvaluationRepository{DB: db})
+	reevaluator.Start(config.LoadBadgeConfig().ReevaluationInterval)
 
 	// Hämta och starta routern
-	r := router.InitializeAndGetRouter()
+	r := router.InitializeAndGetRouter(backfiller, confluenceWebhook, reevaluator)
 
 	// Starta webbservern
 	port := "8081"
@@ -2,13 +2,63 @@ package models
 
 import "time"
 
+// Aktivitetstyper som synkroniseringen registrerar.
+const (
+	ActivityTypePageCreated     = "PAGE_CREATED"
+	ActivityTypePageUpdated     = "PAGE_UPDATED"
+	ActivityTypeCommentCreated  = "COMMENT_CREATED"
+	ActivityTypeResolvedComment = "RESOLVED_COMMENT"
+	// Dokumentation i Git-repon (Markdown/AsciiDoc)
+	ActivityTypeDocCreated = "DOC_CREATED"
+	ActivityTypeDocUpdated = "DOC_UPDATED"
+)
+
+// ActivityTypes är alla kända aktivitetstyper.
+var ActivityTypes = []string{
+	ActivityTypePageCreated,
+	ActivityTypePageUpdated,
+	ActivityTypeCommentCreated,
+	ActivityTypeResolvedComment,
+	ActivityTypeDocCreated,
+	ActivityTypeDocUpdated,
+}
+
 // Activity represents a single point-scoring event in the database.
 type Activity struct {
-	ID                      int64     `json:"id"`
-	UserID                  int64     `json:"userId"`
-	ConfluencePageID        string    `json:"-"` // Internal use, hide from JSON
-	ConfluenceVersionNumber int       `json:"-"` // Internal use, hide from JSON
-	ActivityType            string    `json:"activityType"`
-	PointsAwarded           int       `json:"pointsAwarded"`
-	CreatedAt               time.Time `json:"createdAt"`
+	ID     int64 `json:"id"`
+	UserID int64 `json:"userId"`
+	// Source är aktivitetskällan, t.ex. "confluence"
+	Source                  string `json:"source"`
+	ConfluencePageID        string `json:"-"` // Internal use, hide from JSON
+	ConfluenceVersionNumber int    `json:"-"` // Internal use, hide from JSON
+	ActivityType            string `json:"activityType"`
+	PointsAwarded           int    `json:"pointsAwarded"`
+	// RawPoints är poängen enligt poängregeln innan dags-/veckotak och avtagande poäng
+	RawPoints int `json:"rawPoints"`
+	// DiffBreakdown är vad som ändrades i en uppdatering, nil för typer som inte poängsätts efter storlek
+	DiffBreakdown *DiffBreakdown `json:"diffBreakdown,omitempty"`
+	// ContentHash är en hash av innehållets synliga text efter ändringen, tom om källan inte har någon
+	ContentHash string `json:"-"`
+	// ScoringRuleID är poängregeln som gav poängen, nil för manuella aktiviteter och standardregler
+	ScoringRuleID *int64 `json:"scoringRuleId,omitempty"`
+	// OccurredAt är när händelsen skedde i Confluence, CreatedAt är när den registrerades hos oss
+	OccurredAt time.Time `json:"occurredAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+	SpaceKey   string    `json:"spaceKey,omitempty"`
+	// PagePath är sidans förfäder följt av sidan själv. För kommentarer är det sidan de sitter på.
+	PagePath []string `json:"-"`
+}
+
+// DiffBreakdown beskriver hur stor en ändring av en sida eller ett dokument är. Size är värdet
+// som jämförs med poängregelns trösklar.
+type DiffBreakdown struct {
+	WordsInserted   int `json:"wordsInserted"`
+	WordsDeleted    int `json:"wordsDeleted"`
+	CharsInserted   int `json:"charsInserted"`
+	CharsDeleted    int `json:"charsDeleted"`
+	HeadingsAdded   int `json:"headingsAdded"`
+	TablesAdded     int `json:"tablesAdded"`
+	CodeBlocksAdded int `json:"codeBlocksAdded"`
+	ImagesAdded     int `json:"imagesAdded"`
+	Size            int `json:"size"`
 }
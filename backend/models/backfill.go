@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Statusar för ett backfill-jobb.
+const (
+	BackfillStatusPending   = "pending"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// BackfillJob är en körning som läser in historisk aktivitet från ett Confluence-space.
+// From och To avgränsar vilka händelser som räknas; nil betyder obegränsat åt det hållet.
+type BackfillJob struct {
+	ID                int64      `json:"id"`
+	SpaceKey          string     `json:"spaceKey"`
+	From              *time.Time `json:"from,omitempty"`
+	To                *time.Time `json:"to,omitempty"`
+	Status            string     `json:"status"`
+	PagesDone         int        `json:"pagesDone"`
+	ActivitiesCreated int        `json:"activitiesCreated"`
+	Error             string     `json:"error,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	StartedAt         *time.Time `json:"startedAt,omitempty"`
+	FinishedAt        *time.Time `json:"finishedAt,omitempty"`
+}
+
+// InRange avgör om en händelse vid tidpunkten t ligger inom jobbets intervall. To är exklusiv.
+func (j *BackfillJob) InRange(t time.Time) bool {
+	if j.From != nil && t.Before(*j.From) {
+		return false
+	}
+	if j.To != nil && !t.Before(*j.To) {
+		return false
+	}
+	return true
+}
+
+// ParseBackfillDate tolkar ett datum (YYYY-MM-DD) i UTC. Ett tomt värde betyder obegränsat.
+// Slutdatum är inklusive, så för endOfRange returneras starten på dagen efter.
+func ParseBackfillDate(value string, endOfRange bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -5,6 +5,25 @@ import (
 	"time"
 )
 
+// Mått som badge-villkor kan bygga på. Räknarna heter som kolumnerna i user_stats.
+const (
+	BadgeMetricComments         = "total_comments"
+	BadgeMetricCreatedPages     = "total_created_pages"
+	BadgeMetricEditsMade        = "total_edits_made"
+	BadgeMetricResolvedComments = "total_resolved_comments"
+	BadgeMetricLifetimePoints   = "lifetime_points"
+	// Sviterna räknas som den längsta sviten, så en badge behålls även om sviten bryts
+	BadgeMetricDailyStreak  = "daily_streak"
+	BadgeMetricWeeklyStreak = "weekly_streak"
+)
+
+// BadgeCriteriaComposite är criteria_type för badges vars villkor kombinerar flera mått.
+const BadgeCriteriaComposite = "composite"
+
+// BadgeCriteriaManual är criteria_type för badges som en admin delar ut med en motivering,
+// direkt eller efter en nominering från en kollega.
+const BadgeCriteriaManual = "manual"
+
 type Badge struct {
 	ID            int64          `json:"id"`
 	Name          string         `json:"name"`
@@ -12,11 +31,139 @@ type Badge struct {
 	IconUrl       sql.NullString `json:"icon_url"`
 	CriteriaValue int            `json:"criteria_value"`
 	CriteriaType  string         `json:"criteria_type"`
+	// Criteria är villkoren som JSON. Saknas de gäller criteria_type >= criteria_value
+	Criteria *BadgeCriteria `json:"criteria,omitempty"`
+	// Series och Tier grupperar badges i stegar, t.ex. "documenter" nivå 1-4
+	Series string `json:"series,omitempty"`
+	Tier   int    `json:"tier,omitempty"`
+	// Revocable badges tas tillbaka vid omvärdering om villkoren inte längre uppfylls,
+	// andra behåller användaren även om gränsen har höjts
+	Revocable bool `json:"revocable"`
+}
+
+// BadgeCriteria är ett villkor för en badge: antingen ett mått som ska nå Min,
+// eller en kombination där alla (All) eller något (Any) av villkoren ska vara uppfyllda.
+type BadgeCriteria struct {
+	All []BadgeCriteria `json:"all,omitempty"`
+	Any []BadgeCriteria `json:"any,omitempty"`
+
+	Metric string `json:"metric,omitempty"`
+	Min    int    `json:"min,omitempty"`
+	// SpaceKey räknar bara aktiviteter i ett visst space
+	SpaceKey string `json:"spaceKey,omitempty"`
+	// WindowDays räknar det bästa intervallet om så många dagar i rad, t.ex. 7 för "på en vecka"
+	WindowDays int `json:"windowDays,omitempty"`
 }
 
 type UserBadge struct {
-	UserID    int64     `json:"user_id"`
-	BadgeID   int64     `json:"badge_id"`
-	AwardedAt time.Time `json:"awarded_at"`
-	Progress  int       `json:"progress"`
+	UserID  int64 `json:"user_id"`
+	BadgeID int64 `json:"badge_id"`
+	// AwardedAt är nil tills badgen är upplåst, raden håller då bara progress
+	AwardedAt *time.Time `json:"awarded_at"`
+	Progress  int        `json:"progress"`
+	// Citation är motiveringen för manuella badges och visas publikt
+	Citation        string `json:"citation,omitempty"`
+	AwardedByUserID *int64 `json:"awarded_by_user_id,omitempty"`
+	// Grandfathered är true om badgen behålls fast villkoren inte längre uppfylls
+	Grandfathered bool `json:"grandfathered"`
+}
+
+// BadgeProgress är hur långt en användare har kommit mot en badge.
+type BadgeProgress struct {
+	Badge  Badge `json:"badge"`
+	Value  int   `json:"value"`
+	Target int   `json:"target"`
+	// Percent är högst 100, och 100 för upplåsta badges
+	Percent int  `json:"percent"`
+	Earned  bool `json:"earned"`
+	// Automatic är false för badges som inte delas ut av villkor, t.ex. tävlingspriser
+	Automatic bool       `json:"automatic"`
+	AwardedAt *time.Time `json:"awardedAt,omitempty"`
+	Citation  string     `json:"citation,omitempty"`
+}
+
+// BadgeSeriesProgress är progress för alla nivåer i en serie, lägsta nivån först.
+type BadgeSeriesProgress struct {
+	Series string `json:"series"`
+	// Tier är den högsta upplåsta nivån, 0 om ingen är upplåst
+	Tier   int             `json:"tier"`
+	Badges []BadgeProgress `json:"badges"`
+}
+
+// UserBadgeProgress är progress för alla badges, grupperade i serier. Badges utan serie ligger i Badges.
+type UserBadgeProgress struct {
+	Series []BadgeSeriesProgress `json:"series"`
+	Badges []BadgeProgress       `json:"badges"`
+}
+
+// Vad som startade en omvärdering av badges.
+const (
+	BadgeEvaluationTriggerManual    = "manual"
+	BadgeEvaluationTriggerScheduled = "scheduled"
+)
+
+// Statusar för en omvärdering.
+const (
+	BadgeEvaluationStatusRunning   = "running"
+	BadgeEvaluationStatusCompleted = "completed"
+	BadgeEvaluationStatusFailed    = "failed"
+)
+
+// Ändringar som en omvärdering kan ge.
+const (
+	BadgeChangeAwarded = "awarded"
+	BadgeChangeRevoked = "revoked"
+	// BadgeChangeGrandfathered är en badge som inte längre uppfyller villkoren men behålls
+	BadgeChangeGrandfathered = "grandfathered"
+)
+
+// BadgeChange är en ändring för en användare och badge vid omvärdering.
+type BadgeChange struct {
+	UserID    int64  `json:"userId"`
+	BadgeID   int64  `json:"badgeId"`
+	BadgeName string `json:"badgeName"`
+	Change    string `json:"change"`
+	Value     int    `json:"value"`
+	Target    int    `json:"target"`
+}
+
+// BadgeEvaluationRun är en körning som räknar om alla badges för alla användare.
+// Med DryRun sparas bara vilka ändringar som skulle ha gjorts.
+type BadgeEvaluationRun struct {
+	ID                int64         `json:"id"`
+	Trigger           string        `json:"trigger"`
+	DryRun            bool          `json:"dryRun"`
+	Status            string        `json:"status"`
+	Awarded           int           `json:"awarded"`
+	Revoked           int           `json:"revoked"`
+	Grandfathered     int           `json:"grandfathered"`
+	Changes           []BadgeChange `json:"changes,omitempty"`
+	Error             string        `json:"error,omitempty"`
+	TriggeredByUserID *int64        `json:"triggeredByUserId,omitempty"`
+	StartedAt         time.Time     `json:"startedAt"`
+	FinishedAt        *time.Time    `json:"finishedAt,omitempty"`
+}
+
+// Statusar för en nominering.
+const (
+	NominationStatusPending  = "pending"
+	NominationStatusApproved = "approved"
+	NominationStatusRejected = "rejected"
+)
+
+// BadgeNomination är en nominering av en kollega till en manuell badge. Badgen delas ut
+// med nomineringens motivering när en admin godkänner den.
+type BadgeNomination struct {
+	ID                int64      `json:"id"`
+	BadgeID           int64      `json:"badgeId"`
+	BadgeName         string     `json:"badgeName"`
+	NomineeUserID     int64      `json:"nomineeUserId"`
+	NomineeName       string     `json:"nomineeName"`
+	NominatedByUserID int64      `json:"nominatedByUserId"`
+	NominatedByName   string     `json:"nominatedByName"`
+	Citation          string     `json:"citation"`
+	Status            string     `json:"status"`
+	ReviewedByUserID  *int64     `json:"reviewedByUserId,omitempty"`
+	ReviewedAt        *time.Time `json:"reviewedAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
 }
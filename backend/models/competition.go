@@ -5,6 +5,19 @@ import (
 	"time"
 )
 
+// Tävlingstyper. I en lagtävling räknas medlemmarnas poäng via user_teams.
+const (
+	CompetitionTypeIndividual = "individual"
+	CompetitionTypeTeam       = "team"
+)
+
+// Statusar för en deltagare i en tävling.
+const (
+	ParticipantStatusInvited = "invited"
+	ParticipantStatusJoined  = "joined"
+	ParticipantStatusLeft    = "left"
+)
+
 // Competition represents a contest or event within a specific timeframe.
 type Competition struct {
 	ID              int64          `json:"id"`
@@ -14,8 +27,98 @@ type Competition struct {
 	EndDate         time.Time      `json:"endDate"`
 	CreatedByUserID sql.NullInt64  `json:"-"` // Internal use, hide from JSON
 	CreatedAt       time.Time      `json:"createdAt"`
+	CompetitionType string         `json:"competitionType"`
+	InviteOnly      bool           `json:"inviteOnly"`
+	// PrizeBadgeID delas ut till de PrizeTopN bästa när tävlingen avslutas.
+	PrizeBadgeID *int64           `json:"prizeBadgeId,omitempty"`
+	PrizeTopN    int              `json:"prizeTopN"`
+	FinalizedAt  *time.Time       `json:"finalizedAt,omitempty"`
+	Rules        CompetitionRules `json:"rules"`
 
 	// This field does not exist in the database.
 	// It will be calculated in Go before sending the JSON response.
 	Status string `json:"status,omitempty"`
 }
+
+// CompetitionRules begränsar vilka aktiviteter som räknas i en tävling.
+// Tomma listor betyder att allt räknas.
+type CompetitionRules struct {
+	// ActivityTypes är de aktivitetstyper som räknas, t.ex. "RESOLVED_COMMENT".
+	ActivityTypes []string `json:"activityTypes,omitempty"`
+	// Multipliers multiplicerar poängen per aktivitetstyp, t.ex. {"PAGE_CREATED": 2}.
+	Multipliers map[string]float64 `json:"multipliers,omitempty"`
+	// SpaceKeys är de Confluence-spaces som räknas.
+	SpaceKeys []string `json:"spaceKeys,omitempty"`
+	// AncestorPageIDs räknar bara sidor (och deras kommentarer) som ligger under någon av sidorna.
+	AncestorPageIDs []string `json:"ancestorPageIds,omitempty"`
+}
+
+// Multiplier returnerar multiplikatorn för en aktivitetstyp (1 om ingen är satt).
+func (r CompetitionRules) Multiplier(activityType string) float64 {
+	if m, ok := r.Multipliers[activityType]; ok {
+		return m
+	}
+	return 1
+}
+
+// CompetitionParticipant är en användare eller ett team som är anmält till en tävling.
+// Exakt ett av UserID och TeamID är satt.
+type CompetitionParticipant struct {
+	ID            int64     `json:"id"`
+	CompetitionID int64     `json:"competitionId"`
+	UserID        *int64    `json:"userId,omitempty"`
+	TeamID        *int64    `json:"teamId,omitempty"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status"`
+	JoinedAt      time.Time `json:"joinedAt"`
+}
+
+// CompetitionLeaderboardEntry är en användares placering i en tävling.
+type CompetitionLeaderboardEntry struct {
+	Rank        int            `json:"rank"`
+	UserID      int64          `json:"userId"`
+	DisplayName string         `json:"displayName"`
+	AvatarURL   sql.NullString `json:"avatarUrl"`
+	TotalPoints int            `json:"totalPoints"`
+	// Breakdown är poängen uppdelade per aktivitetstyp, t.ex. "PAGE_CREATED": 22.
+	Breakdown map[string]int `json:"breakdown"`
+}
+
+// CompetitionTeamLeaderboardEntry är ett teams placering i en tävling.
+type CompetitionTeamLeaderboardEntry struct {
+	Rank        int            `json:"rank"`
+	TeamID      int64          `json:"teamId"`
+	TeamName    string         `json:"teamName"`
+	TotalPoints int            `json:"totalPoints"`
+	Breakdown   map[string]int `json:"breakdown"`
+}
+
+// CompetitionLeaderboard är hela resultatlistan för en tävling.
+type CompetitionLeaderboard struct {
+	CompetitionID   int64                             `json:"competitionId"`
+	CompetitionType string                            `json:"competitionType"`
+	StartDate       time.Time                         `json:"startDate"`
+	EndDate         time.Time                         `json:"endDate"`
+	Users           []CompetitionLeaderboardEntry     `json:"users"`
+	Teams           []CompetitionTeamLeaderboardEntry `json:"teams,omitempty"`
+}
+
+// CompetitionResult är en fryst placering som sparas när tävlingen avslutas.
+// Namnet sparas också så att resultatet inte ändras om användaren eller teamet byter namn.
+type CompetitionResult struct {
+	Rank         int            `json:"rank"`
+	UserID       *int64         `json:"userId,omitempty"`
+	TeamID       *int64         `json:"teamId,omitempty"`
+	Name         string         `json:"name"`
+	TotalPoints  int            `json:"totalPoints"`
+	Breakdown    map[string]int `json:"breakdown"`
+	PrizeAwarded bool           `json:"prizeAwarded"`
+}
+
+// CompetitionResults är det arkiverade slutresultatet för en avslutad tävling.
+type CompetitionResults struct {
+	CompetitionID int64               `json:"competitionId"`
+	FinalizedAt   time.Time           `json:"finalizedAt"`
+	Users         []CompetitionResult `json:"users"`
+	Teams         []CompetitionResult `json:"teams,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Typer av rader i poängliggaren.
+const (
+	// LedgerEntryAward är poäng för en aktivitet
+	LedgerEntryAward = "award"
+	// LedgerEntryAdjustment är en manuell ändring av en admin, eller en ändrad aktivitet
+	LedgerEntryAdjustment = "adjustment"
+	// LedgerEntryReversal tar bort poängen från en tidigare rad eller en borttagen aktivitet
+	LedgerEntryReversal = "reversal"
+	// LedgerEntrySeasonReset nollställer total_points inför en ny säsong, lifetime_points påverkas inte
+	LedgerEntrySeasonReset = "season_reset"
+	// LedgerEntryOpeningBalance är poängen användaren hade innan liggaren infördes
+	LedgerEntryOpeningBalance = "opening_balance"
+)
+
+// LedgerEntry är en rad i poängliggaren. Points är ändringen av total_points och LifetimePoints
+// ändringen av lifetime_points. ActorUserID är admin som gjorde ändringen, nil för systemet.
+type LedgerEntry struct {
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"userId"`
+	EntryType       string    `json:"entryType"`
+	Points          int       `json:"points"`
+	LifetimePoints  int       `json:"lifetimePoints"`
+	ActivityID      *int64    `json:"activityId,omitempty"`
+	ReversesEntryID *int64    `json:"reversesEntryId,omitempty"`
+	Reason          string    `json:"reason"`
+	ActorUserID     *int64    `json:"actorUserId,omitempty"`
+	OccurredAt      time.Time `json:"occurredAt"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
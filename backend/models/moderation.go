@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Skäl till att en aktivitet flaggas av missbruksskyddet.
+const (
+	FlagReasonTrivialEdit    = "trivial_edit"
+	FlagReasonTrivialComment = "trivial_comment"
+	FlagReasonSelfResolution = "self_resolution"
+	FlagReasonRateLimit      = "rate_limit"
+	FlagReasonRevert         = "revert"
+)
+
+// Status för en flagga i modereringskön.
+const (
+	FlagStatusPending  = "pending"
+	FlagStatusApproved = "approved"
+	FlagStatusRejected = "rejected"
+)
+
+// ActivityFlag är en misstänkt aktivitet. Poängen hålls inne tills en admin har granskat den:
+// godkänns den får användaren poängen, avvisas den blir den kvar på 0.
+type ActivityFlag struct {
+	ID               int64      `json:"id"`
+	ActivityID       int64      `json:"activityId"`
+	Reasons          []string   `json:"reasons"`
+	PointsWithheld   int        `json:"pointsWithheld"`
+	Status           string     `json:"status"`
+	ReviewedByUserID *int64     `json:"reviewedByUserId,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+
+	// Aktiviteten som flaggades, för att kunna granska utan fler anrop
+	UserID       int64     `json:"userId"`
+	DisplayName  string    `json:"displayName"`
+	ActivityType string    `json:"activityType"`
+	Source       string    `json:"source"`
+	ContentID    string    `json:"contentId"`
+	SpaceKey     string    `json:"spaceKey,omitempty"`
+	OccurredAt   time.Time `json:"occurredAt"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// ScoringRule bestämmer hur många poäng en aktivitetstyp ger. Regler ändras aldrig i efterhand,
+// en ändring sparas som en ny version så att varje aktivitet kan spåras till regeln som gav poängen.
+type ScoringRule struct {
+	ID           int64  `json:"id"`
+	ActivityType string `json:"activityType"`
+	Version      int    `json:"version"`
+	BasePoints   int    `json:"basePoints"`
+	// DiffThresholds är gränser för ändringens storlek. Poängen blir BasePoints gånger
+	// (index för första gränsen som ändringen ryms inom + 1). Tom lista ger alltid BasePoints.
+	DiffThresholds []int `json:"diffThresholds"`
+	// MaxPoints är taket för en enskild aktivitet, nil betyder inget tak
+	MaxPoints *int `json:"maxPoints,omitempty"`
+	// DailyCap och WeeklyCap är hur många poäng en användare kan få för typen per dag/vecka (UTC,
+	// veckan börjar på måndag). nil betyder inget tak.
+	DailyCap  *int `json:"dailyCap,omitempty"`
+	WeeklyCap *int `json:"weeklyCap,omitempty"`
+	// DiminishingFactor ger avtagande poäng: den n:te aktiviteten av typen samma dag får
+	// poängen gånger DiminishingFactor^(n-1). nil eller 1 betyder fulla poäng varje gång.
+	DiminishingFactor *float64  `json:"diminishingFactor,omitempty"`
+	Active            bool      `json:"active"`
+	CreatedByUserID   *int64    `json:"createdByUserId,omitempty"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// Points räknar ut poängen för en ändring av storleken diff.
+func (r *ScoringRule) Points(diff int) int {
+	complexity := len(r.DiffThresholds) + 1
+	for i, limit := range r.DiffThresholds {
+		if diff <= limit {
+			complexity = i + 1
+			break
+		}
+	}
+
+	points := r.BasePoints * complexity
+	if r.MaxPoints != nil && points > *r.MaxPoints {
+		points = *r.MaxPoints
+	}
+	return points
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Status för en säsong.
+const (
+	SeasonStatusUpcoming = "upcoming"
+	SeasonStatusActive   = "active"
+	// SeasonStatusEnded är en säsong som har tagit slut men inte arkiverats än
+	SeasonStatusEnded    = "ended"
+	SeasonStatusArchived = "archived"
+)
+
+// Season är en period som total_points räknas över. När säsongen tar slut arkiveras
+// ställningen och total_points nollställs, lifetime_points påverkas inte.
+type Season struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	// AutoRenew skapar nästa säsong med samma längd när den här arkiveras
+	AutoRenew       bool       `json:"autoRenew"`
+	Status          string     `json:"status"`
+	ArchivedAt      *time.Time `json:"archivedAt,omitempty"`
+	CreatedByUserID *int64     `json:"createdByUserId,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// StatusAt räknar ut säsongens status vid tidpunkten now.
+func (s *Season) StatusAt(now time.Time) string {
+	switch {
+	case s.ArchivedAt != nil:
+		return SeasonStatusArchived
+	case now.Before(s.StartDate):
+		return SeasonStatusUpcoming
+	case now.Before(s.EndDate):
+		return SeasonStatusActive
+	default:
+		return SeasonStatusEnded
+	}
+}
+
+// SeasonStanding är en användares poäng och placering under en säsong.
+type SeasonStanding struct {
+	UserID      int64  `json:"userId"`
+	DisplayName string `json:"displayName"`
+	Points      int    `json:"points"`
+	Rank        int    `json:"rank"`
+}
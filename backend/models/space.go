@@ -0,0 +1,25 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// spaceKeyPattern är tecknen som får förekomma i en space-nyckel. Nyckeln skrivs in i CQL,
+// så citattecken och backslash får inte komma med.
+var spaceKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_~-]+$`)
+
+// ConfluenceSpace är ett Confluence-space som synkroniseringen hämtar aktiviteter från.
+type ConfluenceSpace struct {
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	// SyncCursor är tidpunkten för den senaste ändringen som synkroniseringen har sett
+	SyncCursor *time.Time `json:"syncCursor,omitempty"`
+}
+
+// ValidSpaceKey avgör om key är en giltig Confluence space-nyckel, t.ex. "DOCS" eller "~anna".
+func ValidSpaceKey(key string) bool {
+	return spaceKeyPattern.MatchString(key)
+}
@@ -18,11 +18,27 @@ type User struct {
 }
 
 type UserStats struct {
-	UserID                int64 `json:"id"`
-	TotalComments         int   `json:"totalComments"`
-	TotalEdits            int   `json:"totalEdits"`
-	TotalCreatedPages     int   `json:"totalCreatedPages"`
-	TotalResolvedComments int   `json:"totalResolvedComments"`
+	UserID                int64        `json:"id"`
+	TotalComments         int          `json:"totalComments"`
+	TotalEdits            int          `json:"totalEdits"`
+	TotalCreatedPages     int          `json:"totalCreatedPages"`
+	TotalResolvedComments int          `json:"totalResolvedComments"`
+	Streaks               *UserStreaks `json:"streaks,omitempty"`
+}
+
+// Streak är antal dagar eller veckor med aktivitet i rad. Dagar och veckor utan aktivitet
+// inom karenstiden bryter inte sviten men räknas inte heller.
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+	// LastActive är starten på den senaste dagen eller veckan med aktivitet
+	LastActive *time.Time `json:"lastActive,omitempty"`
+}
+
+// UserStreaks är en användares dagliga och veckovisa sviter.
+type UserStreaks struct {
+	Daily  Streak `json:"daily"`
+	Weekly Streak `json:"weekly"`
 }
 
 // UserTopStat Den här används för top-användarna (Top Commenter, Top Editor osv)
@@ -31,3 +47,11 @@ type UserTopStat struct {
 	AvatarURL   string `json:"avatar_url"`
 	Count       int    `json:"count"`
 }
+
+// UserIdentity kopplar ett användar-ID i en aktivitetskälla (t.ex. en e-postadress i Git) till en användare.
+type UserIdentity struct {
+	Source     string    `json:"source"`
+	ExternalID string    `json:"externalId"`
+	UserID     int64     `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -1,17 +1,20 @@
 package router
 
 import (
+	"gamification-api/backend/database"
 	"gamification-api/backend/handlers"
 	"github.com/gorilla/mux"
 )
 
-func RegisterActivityRoutes(r *mux.Router, h *handlers.ActivityHandler) {
+// RegisterActivityRoutes registrerar endpoints för aktiviteter. Att skapa, ändra och ta bort kräver admin,
+// eftersom det ger rader i poängliggaren.
+func RegisterActivityRoutes(r *mux.Router, h *handlers.ActivityHandler, userRepo *database.UserRepository) {
 	s := r.PathPrefix("/activities").Subrouter()
 
 	s.HandleFunc("", h.GetAllActivitiesHandler).Methods("GET")
-	s.HandleFunc("", h.CreateActivityHandler).Methods("POST")
+	s.Handle("", RequireAdmin(userRepo, h.CreateActivityHandler)).Methods("POST")
 
 	s.HandleFunc("/{id:[0-9]+}", h.GetActivityByIDHandler).Methods("GET")
-	s.HandleFunc("/{id:[0-9]+}", h.UpdateActivityHandler).Methods("PUT")
-	s.HandleFunc("/{id:[0-9]+}", h.DeleteActivityHandler).Methods("DELETE")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.UpdateActivityHandler)).Methods("PUT")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.DeleteActivityHandler)).Methods("DELETE")
 }
\ No newline at end of file
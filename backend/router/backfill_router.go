@@ -0,0 +1,18 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBackfillRoutes registrerar endpoints för att läsa in historisk Confluence-aktivitet. Alla kräver admin.
+func RegisterBackfillRoutes(r *mux.Router, h *handlers.BackfillHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/backfill").Subrouter()
+
+	s.Handle("", RequireAdmin(userRepo, h.GetAllJobsHandler)).Methods("GET")
+	s.Handle("", RequireAdmin(userRepo, h.CreateJobHandler)).Methods("POST")
+	s.Handle("/{id}", RequireAdmin(userRepo, h.GetJobHandler)).Methods("GET")
+	s.Handle("/{id}/resume", RequireAdmin(userRepo, h.ResumeJobHandler)).Methods("POST")
+}
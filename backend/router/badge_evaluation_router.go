@@ -0,0 +1,17 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBadgeEvaluationRoutes registrerar endpoints för omvärdering av badges. Alla kräver admin.
+func RegisterBadgeEvaluationRoutes(r *mux.Router, h *handlers.BadgeEvaluationHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/badges/evaluations").Subrouter()
+
+	s.Handle("", RequireAdmin(userRepo, h.GetAllRunsHandler)).Methods("GET")
+	s.Handle("", RequireAdmin(userRepo, h.CreateRunHandler)).Methods("POST")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.GetRunHandler)).Methods("GET")
+}
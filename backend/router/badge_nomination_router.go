@@ -0,0 +1,21 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterBadgeNominationRoutes registrerar nomineringar till manuella badges. Alla inloggade
+// kan nominera en kollega, granskningen kräver admin.
+func RegisterBadgeNominationRoutes(r *mux.Router, h *handlers.UserBadgeHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/nominations").Subrouter()
+
+	s.Handle("", JwtMiddleware(http.HandlerFunc(h.CreateNominationHandler))).Methods("POST")
+	s.Handle("", RequireAdmin(userRepo, h.GetNominationsHandler)).Methods("GET")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.GetNominationHandler)).Methods("GET")
+	s.Handle("/{id:[0-9]+}/approve", RequireAdmin(userRepo, h.ApproveNominationHandler)).Methods("POST")
+	s.Handle("/{id:[0-9]+}/reject", RequireAdmin(userRepo, h.RejectNominationHandler)).Methods("POST")
+}
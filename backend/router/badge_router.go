@@ -1,27 +1,30 @@
 package router
 
 import (
+	"gamification-api/backend/database"
 	"gamification-api/backend/handlers"
 	"github.com/gorilla/mux"
 )
 
-func RegisterBadgeRoutes(r *mux.Router, h *handlers.BadgeHandler) {
+// RegisterBadgeRoutes registrerar endpoints för badge-definitioner. Att ändra dem kräver admin,
+// eftersom omvärderingen delar ut och tar tillbaka badges utifrån villkoren.
+func RegisterBadgeRoutes(r *mux.Router, h *handlers.BadgeHandler, userRepo *database.UserRepository) {
 	s := r.PathPrefix("/badges").Subrouter()
 	s.HandleFunc("", h.GetAllBadgesHandler).Methods("GET")
-	s.HandleFunc("", h.CreateBadgeHandler).Methods("POST")
+	s.Handle("", RequireAdmin(userRepo, h.CreateBadgeHandler)).Methods("POST")
 
 	s.HandleFunc("/{id:[0-9]+}", h.GetBadgeByIDHandler).Methods("GET")
-	s.HandleFunc("/{id:[0-9]+}", h.UpdateBadgeHandler).Methods("PUT")
-	s.HandleFunc("/{id:[0-9]+}", h.DeleteBadgeHandler).Methods("DELETE")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.UpdateBadgeHandler)).Methods("PUT")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.DeleteBadgeHandler)).Methods("DELETE")
 }
 
-func RegisterUserBadgeRoutes(r *mux.Router, h *handlers.UserBadgeHandler) {
+func RegisterUserBadgeRoutes(r *mux.Router, h *handlers.UserBadgeHandler, userRepo *database.UserRepository) {
 	s := r.PathPrefix("/userbadges").Subrouter()
 	s.HandleFunc("", h.GetAllUserBadgesHandler).Methods("GET")
-	s.HandleFunc("", h.CreateUserBadgeHandler).Methods("POST")
+	s.Handle("", RequireAdmin(userRepo, h.CreateUserBadgeHandler)).Methods("POST")
 
 	// Notera den mer komplexa URL-strukturen här
 	s.HandleFunc("/{userId:[0-9]+}/{badgeId:[0-9]+}", h.GetUserBadgeHandler).Methods("GET")
-	s.HandleFunc("/{userId:[0-9]+}/{badgeId:[0-9]+}", h.UpdateUserBadgeHandler).Methods("PUT")
-	s.HandleFunc("/{userId:[0-9]+}/{badgeId:[0-9]+}", h.DeleteUserBadgeHandler).Methods("DELETE")
+	s.Handle("/{userId:[0-9]+}/{badgeId:[0-9]+}", RequireAdmin(userRepo, h.UpdateUserBadgeHandler)).Methods("PUT")
+	s.Handle("/{userId:[0-9]+}/{badgeId:[0-9]+}", RequireAdmin(userRepo, h.DeleteUserBadgeHandler)).Methods("DELETE")
 }
\ No newline at end of file
@@ -2,29 +2,42 @@ package router
 
 import (
 	"gamification-api/backend/handlers" // Byt ut mot ert modulnamn
+	"net/http"
+
 	"github.com/gorilla/mux"
 )
 
 // RegisterCompetitionRoutes registrerar alla vägar som har med tävlingar att göra.
 func RegisterCompetitionRoutes(r *mux.Router, h *handlers.CompetitionHandler) {
-	// Skapa en subrouter för att undvika att skriva /api/v1 hela tiden
-	s := r.PathPrefix("/api/v1/competitions").Subrouter()
+	// r är redan /api/v1-subroutern
+	s := r.PathPrefix("/competitions").Subrouter()
 
 	// GET /api/v1/competitions - Hämtar alla tävlingar
-	// POST /api/v1/competitions - Skapar en ny tävling
+	// POST /api/v1/competitions - Skapar en ny tävling (kräver JWT, skaparen sparas)
 	s.HandleFunc("", h.GetAllCompetitionsHandler).Methods("GET")
-	s.HandleFunc("", h.CreateCompetitionHandler).Methods("POST")
+	s.Handle("", JwtMiddleware(http.HandlerFunc(h.CreateCompetitionHandler))).Methods("POST")
 
 	// GET /api/v1/competitions/{id} - Hämtar en specifik tävling
 	s.HandleFunc("/{id:[0-9]+}", h.GetCompetitionByIDHandler).Methods("GET")
 
 	// GET /api/v1/competitions/{id}/leaderboard - Hämtar leaderboard för en tävling
-	// s.HandleFunc("/{id:[0-9]+}/leaderboard", h.GetCompetitionLeaderboardHandler).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/leaderboard", h.GetCompetitionLeaderboardHandler).Methods("GET")
+
+	// GET /api/v1/competitions/{id}/results - Hämtar det arkiverade slutresultatet
+	s.HandleFunc("/{id:[0-9]+}/results", h.GetCompetitionResultsHandler).Methods("GET")
+
+	// Deltagare. Join/leave gäller den inloggade användaren och kräver JWT.
+	// Att anmäla eller ta bort andra kräver skaparen eller en admin, det kontrolleras i handlern.
+	s.HandleFunc("/{id:[0-9]+}/participants", h.GetParticipantsHandler).Methods("GET")
+	s.Handle("/{id:[0-9]+}/participants", JwtMiddleware(http.HandlerFunc(h.AddParticipantHandler))).Methods("POST")
+	s.Handle("/{id:[0-9]+}/participants/{participantId:[0-9]+}", JwtMiddleware(http.HandlerFunc(h.RemoveParticipantHandler))).Methods("DELETE")
+	s.Handle("/{id:[0-9]+}/join", JwtMiddleware(http.HandlerFunc(h.JoinCompetitionHandler))).Methods("POST")
+	s.Handle("/{id:[0-9]+}/leave", JwtMiddleware(http.HandlerFunc(h.LeaveCompetitionHandler))).Methods("POST")
 
-	// PUT /api/v1/competitions/{id} - Uppdaterar en tävling
-	// s.HandleFunc("/{id:[0-9]+}", h.UpdateCompetitionHandler).Methods("PUT")
+	// PUT /api/v1/competitions/{id} - Uppdaterar en tävling (endast skaparen eller admin)
+	s.Handle("/{id:[0-9]+}", JwtMiddleware(http.HandlerFunc(h.UpdateCompetitionHandler))).Methods("PUT")
 
-	// DELETE /api/v1/competitions/{id} - Tar bort en tävling
-	s.HandleFunc("/{id:[0-9]+}", h.DeleteCompetitionHandler).Methods("DELETE")
+	// DELETE /api/v1/competitions/{id} - Tar bort en tävling (endast skaparen eller admin)
+	s.Handle("/{id:[0-9]+}", JwtMiddleware(http.HandlerFunc(h.DeleteCompetitionHandler))).Methods("DELETE")
 }
 
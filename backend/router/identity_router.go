@@ -0,0 +1,17 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterIdentityRoutes registrerar endpoints för att koppla identiteter i andra källor till användare. Alla kräver admin.
+func RegisterIdentityRoutes(r *mux.Router, h *handlers.IdentityHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/users/{id:[0-9]+}/identities").Subrouter()
+
+	s.Handle("", RequireAdmin(userRepo, h.GetUserIdentitiesHandler)).Methods("GET")
+	s.Handle("", RequireAdmin(userRepo, h.LinkIdentityHandler)).Methods("POST")
+	s.Handle("/{source}/{externalId}", RequireAdmin(userRepo, h.UnlinkIdentityHandler)).Methods("DELETE")
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterLedgerRoutes registrerar poängliggaren. Att läsa är öppet, att ändra kräver admin.
+func RegisterLedgerRoutes(r *mux.Router, h *handlers.LedgerHandler, userRepo *database.UserRepository) {
+	r.HandleFunc("/users/{id:[0-9]+}/ledger", h.GetUserLedgerHandler).Methods("GET")
+	r.Handle("/users/{id:[0-9]+}/points", RequireAdmin(userRepo, h.AdjustPointsHandler)).Methods("POST")
+	r.Handle("/ledger/{id:[0-9]+}/reverse", RequireAdmin(userRepo, h.ReverseEntryHandler)).Methods("POST")
+}
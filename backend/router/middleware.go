@@ -4,6 +4,7 @@ import (
 	"context"
 	"gamification-api/backend/auth"
 	"gamification-api/backend/contextkeys" 
+	"gamification-api/backend/database"
 	"net/http"
 	"strings"
 )
@@ -34,3 +35,30 @@ func JwtMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// AdminMiddleware släpper bara igenom användare som är admin. Måste ligga efter JwtMiddleware.
+func AdminMiddleware(userRepo *database.UserRepository, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(contextkeys.UserContextKey).(int64)
+		if !ok {
+			http.Error(w, "Kunde inte hämta användar-ID från token", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := userRepo.GetUserByID(userID)
+		if err != nil {
+			http.Error(w, "Internt serverfel", http.StatusInternalServerError)
+			return
+		}
+		if user == nil || !user.IsAdmin {
+			http.Error(w, "Endast admin har behörighet", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin kräver både giltig JWT och att användaren är admin.
+func RequireAdmin(userRepo *database.UserRepository, handler http.HandlerFunc) http.Handler {
+	return JwtMiddleware(AdminMiddleware(userRepo, handler))
+}
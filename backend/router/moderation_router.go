@@ -0,0 +1,18 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterModerationRoutes registrerar modereringskön. Alla endpoints kräver admin.
+func RegisterModerationRoutes(r *mux.Router, h *handlers.ModerationHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/moderation/flags").Subrouter()
+
+	s.Handle("", RequireAdmin(userRepo, h.GetFlagsHandler)).Methods("GET")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.GetFlagHandler)).Methods("GET")
+	s.Handle("/{id:[0-9]+}/approve", RequireAdmin(userRepo, h.ApproveFlagHandler)).Methods("POST")
+	s.Handle("/{id:[0-9]+}/reject", RequireAdmin(userRepo, h.RejectFlagHandler)).Methods("POST")
+}
@@ -1,8 +1,12 @@
 package router
 
 import (
+	"gamification-api/backend/badges"
+	"gamification-api/backend/config"
 	"gamification-api/backend/database"
 	"gamification-api/backend/handlers"
+	"gamification-api/backend/integrations/confluence"
+	"gamification-api/backend/streaks"
 	"log"
 	"net/http"
 
@@ -22,10 +26,21 @@ type dependencies struct {
 	SystemHandler      *handlers.SystemHandler
 	FileHandler        *handlers.FileHandler
 	leaderBoardHandler *handlers.LeaderboardHandler
+	SpaceHandler       *handlers.SpaceHandler
+	BackfillHandler    *handlers.BackfillHandler
+	ConfluenceWebhook  *confluence.WebhookHandler
+	IdentityHandler    *handlers.IdentityHandler
+	ScoringRuleHandler *handlers.ScoringRuleHandler
+	ModerationHandler  *handlers.ModerationHandler
+	LedgerHandler      *handlers.LedgerHandler
+	SeasonHandler      *handlers.SeasonHandler
+	BadgeEvalHandler   *handlers.BadgeEvaluationHandler
 }
 
 // InitializeAndGetRouter sköter hela setup-processen och returnerar en färdig router.
-func InitializeAndGetRouter() *mux.Router {
+// Backfillern och webhook-mottagaren skapas i main eftersom de behöver Confluence-klienten,
+// och omvärderingen av badges eftersom den ska startas samtidigt som resten av bakgrundsjobben.
+func InitializeAndGetRouter(backfiller *confluence.Backfiller, confluenceWebhook *confluence.WebhookHandler, reevaluator *badges.Reevaluator) *mux.Router {
 	// Steg 1: Anslut till databasen
 	db, err := database.ConnectDB()
 	if err != nil {
@@ -40,23 +55,49 @@ func InitializeAndGetRouter() *mux.Router {
 	teamRepo := &database.TeamRepository{DB: db}
 	userTeamRepo := &database.UserTeamRepository{DB: db}
 	competitionRepo := &database.CompetitionRepository{DB: db}
+	competitionParticipantRepo := &database.CompetitionParticipantRepository{DB: db}
 	systemRepo := &database.SystemRepository{DB: db}
 	leaderBoardRepo := &database.LeaderBoardRepository{DB: db}
 	userStatsRepo := &database.UserStatsRepository{DB: db}
+	spaceRepo := &database.SpaceRepository{DB: db}
+	backfillRepo := &database.BackfillRepository{DB: db}
+	identityRepo := &database.IdentityRepository{DB: db}
+	scoringRuleRepo := &database.ScoringRuleRepository{DB: db}
+	moderationRepo := &database.ModerationRepository{DB: db}
+	ledgerRepo := &database.LedgerRepository{DB: db}
+	seasonRepo := &database.SeasonRepository{DB: db}
+	nominationRepo := &database.BadgeNominationRepository{DB: db}
+	streakTracker := &streaks.Tracker{Repo: activityRepo, Config: config.LoadStreakConfig()}
+	badgeEngine := &badges.Engine{
+		BadgeRepo:     badgeRepo,
+		UserBadgeRepo: userBadgeRepo,
+		MetricRepo:    &database.BadgeMetricRepository{DB: db},
+		UserRepo:      userRepo,
+		Streaks:       streakTracker,
+	}
 
 	// Steg 3: Skapa alla handlers
 	deps := dependencies{
-		UserHandler:        &handlers.UserHandler{Repo: userRepo, UserStatsRepo: userStatsRepo},
+		UserHandler:        &handlers.UserHandler{Repo: userRepo, UserStatsRepo: userStatsRepo, Streaks: streakTracker},
 		AuthHandler:        &handlers.AuthHandler{UserRepo: userRepo},
 		BadgeHandler:       &handlers.BadgeHandler{Repo: badgeRepo},
-		UserBadgeHandler:   &handlers.UserBadgeHandler{Repo: userBadgeRepo},
+		UserBadgeHandler:   &handlers.UserBadgeHandler{Repo: userBadgeRepo, Badges: badgeEngine, BadgeRepo: badgeRepo, UserRepo: userRepo, NominationRepo: nominationRepo},
 		ActivityHandler:    &handlers.ActivityHandler{Repo: activityRepo},
 		TeamHandler:        &handlers.TeamHandler{Repo: teamRepo, UserTeamRepo: userTeamRepo},
 		UserTeamHandler:    &handlers.UserTeamHandler{Repo: userTeamRepo},
-		CompetitionHandler: &handlers.CompetitionHandler{Repo: competitionRepo},
+		CompetitionHandler: &handlers.CompetitionHandler{Repo: competitionRepo, UserRepo: userRepo, ParticipantRepo: competitionParticipantRepo, UserTeamRepo: userTeamRepo, BadgeRepo: badgeRepo},
 		SystemHandler:      &handlers.SystemHandler{Repo: systemRepo},
 		FileHandler:        &handlers.FileHandler{UserRepo: userRepo, BadgeRepo: badgeRepo},
 		leaderBoardHandler: &handlers.LeaderboardHandler{Repo: leaderBoardRepo},
+		SpaceHandler:       &handlers.SpaceHandler{Repo: spaceRepo},
+		BackfillHandler:    &handlers.BackfillHandler{Repo: backfillRepo, Backfiller: backfiller},
+		ConfluenceWebhook:  confluenceWebhook,
+		IdentityHandler:    &handlers.IdentityHandler{Repo: identityRepo, UserRepo: userRepo},
+		ScoringRuleHandler: &handlers.ScoringRuleHandler{Repo: scoringRuleRepo},
+		ModerationHandler:  &handlers.ModerationHandler{Repo: moderationRepo, UserStatsRepo: userStatsRepo, Badges: badgeEngine},
+		LedgerHandler:      &handlers.LedgerHandler{Repo: ledgerRepo, UserRepo: userRepo},
+		SeasonHandler:      &handlers.SeasonHandler{Repo: seasonRepo},
+		BadgeEvalHandler:   &handlers.BadgeEvaluationHandler{Repo: reevaluator.Repo, Reevaluator: reevaluator},
 	}
 
 	// Steg 4: Konfigurera och returnera routern
@@ -83,13 +124,17 @@ func newRouter(deps dependencies) *mux.Router {
 		RegisterCompetitionRoutes(api, deps.CompetitionHandler)
 	}
 	if deps.ActivityHandler != nil {
-		RegisterActivityRoutes(api, deps.ActivityHandler)
+		RegisterActivityRoutes(api, deps.ActivityHandler, deps.UserHandler.Repo)
 	}
 	if deps.BadgeHandler != nil {
-		RegisterBadgeRoutes(api, deps.BadgeHandler)
+		RegisterBadgeRoutes(api, deps.BadgeHandler, deps.UserHandler.Repo)
+	}
+	if deps.BadgeEvalHandler != nil {
+		RegisterBadgeEvaluationRoutes(api, deps.BadgeEvalHandler, deps.UserHandler.Repo)
 	}
 	if deps.UserBadgeHandler != nil {
-		RegisterUserBadgeRoutes(api, deps.UserBadgeHandler)
+		RegisterUserBadgeRoutes(api, deps.UserBadgeHandler, deps.UserHandler.Repo)
+		RegisterBadgeNominationRoutes(api, deps.UserBadgeHandler, deps.UserHandler.Repo)
 	}
 	if deps.TeamHandler != nil {
 		RegisterTeamRoutes(api, deps.TeamHandler)
@@ -105,6 +150,31 @@ func newRouter(deps dependencies) *mux.Router {
 	if deps.leaderBoardHandler != nil {
 		RegisterLeaderboardRoutes(api, deps.leaderBoardHandler)
 	}
+	if deps.SpaceHandler != nil {
+		RegisterSpaceRoutes(api, deps.SpaceHandler, deps.UserHandler.Repo)
+	}
+	if deps.BackfillHandler != nil {
+		RegisterBackfillRoutes(api, deps.BackfillHandler, deps.UserHandler.Repo)
+	}
+	if deps.IdentityHandler != nil {
+		RegisterIdentityRoutes(api, deps.IdentityHandler, deps.UserHandler.Repo)
+	}
+	if deps.ScoringRuleHandler != nil {
+		RegisterScoringRuleRoutes(api, deps.ScoringRuleHandler, deps.UserHandler.Repo)
+	}
+	if deps.ModerationHandler != nil {
+		RegisterModerationRoutes(api, deps.ModerationHandler, deps.UserHandler.Repo)
+	}
+	if deps.LedgerHandler != nil {
+		RegisterLedgerRoutes(api, deps.LedgerHandler, deps.UserHandler.Repo)
+	}
+	if deps.SeasonHandler != nil {
+		RegisterSeasonRoutes(api, deps.SeasonHandler, deps.UserHandler.Repo)
+	}
+	if deps.ConfluenceWebhook != nil {
+		// Autentiseras med signaturen i anropet, inte med JWT
+		api.Handle("/webhooks/confluence", deps.ConfluenceWebhook).Methods("POST")
+	}
 
 	fs := http.FileServer(http.Dir("./static/"))
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterScoringRuleRoutes registrerar endpoints för poängreglerna.
+func RegisterScoringRuleRoutes(r *mux.Router, h *handlers.ScoringRuleHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/scoring-rules").Subrouter()
+
+	s.HandleFunc("", h.GetAllRulesHandler).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", h.GetRuleHandler).Methods("GET")
+
+	// Ändringar kräver admin
+	s.Handle("", RequireAdmin(userRepo, h.CreateRuleHandler)).Methods("POST")
+	s.Handle("/{id:[0-9]+}/activate", RequireAdmin(userRepo, h.ActivateRuleHandler)).Methods("POST")
+}
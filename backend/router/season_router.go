@@ -0,0 +1,22 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterSeasonRoutes registrerar endpoints för säsonger och deras ställningar.
+func RegisterSeasonRoutes(r *mux.Router, h *handlers.SeasonHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/seasons").Subrouter()
+
+	s.HandleFunc("", h.GetAllSeasonsHandler).Methods("GET")
+	s.HandleFunc("/current", h.GetCurrentSeasonHandler).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}", h.GetSeasonHandler).Methods("GET")
+	s.HandleFunc("/{id:[0-9]+}/standings", h.GetStandingsHandler).Methods("GET")
+
+	// Ändringar kräver admin
+	s.Handle("", RequireAdmin(userRepo, h.CreateSeasonHandler)).Methods("POST")
+	s.Handle("/{id:[0-9]+}", RequireAdmin(userRepo, h.DeleteSeasonHandler)).Methods("DELETE")
+}
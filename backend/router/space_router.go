@@ -0,0 +1,19 @@
+package router
+
+import (
+	"gamification-api/backend/database"
+	"gamification-api/backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterSpaceRoutes registrerar endpoints för vilka Confluence-spaces som synkroniseras.
+func RegisterSpaceRoutes(r *mux.Router, h *handlers.SpaceHandler, userRepo *database.UserRepository) {
+	s := r.PathPrefix("/spaces").Subrouter()
+
+	s.HandleFunc("", h.GetAllSpacesHandler).Methods("GET")
+
+	// Ändringar kräver admin
+	s.Handle("", RequireAdmin(userRepo, h.UpsertSpaceHandler)).Methods("POST")
+	s.Handle("/{key}", RequireAdmin(userRepo, h.DeleteSpaceHandler)).Methods("DELETE")
+}
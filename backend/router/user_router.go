@@ -22,5 +22,8 @@ func RegisterUserRoutes(r *mux.Router, userHandler *handlers.UserHandler, userBa
 	s.HandleFunc("/{id:[0-9]+}", userHandler.DeleteUserHandler).Methods("DELETE")
 
 	s.HandleFunc("/{id:[0-9]+}/badges", userBadgeHandler.GetUserBadgesByUserIDHandler).Methods("GET")
+	// POST /api/v1/users/{id}/badges - En admin delar ut en manuell badge med motivering
+	s.Handle("/{id:[0-9]+}/badges", RequireAdmin(userHandler.Repo, userBadgeHandler.GrantBadgeHandler)).Methods("POST")
+	s.HandleFunc("/{id:[0-9]+}/badges/progress", userBadgeHandler.GetUserBadgeProgressHandler).Methods("GET")
 	s.HandleFunc("/{id:[0-9]+}/stats", userHandler.GetUserStatsHandler).Methods("GET")
 }
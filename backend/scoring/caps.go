@@ -0,0 +1,80 @@
+package scoring
+
+import (
+	"gamification-api/backend/models"
+	"math"
+	"time"
+)
+
+// applyCaps tillämpar avtagande poäng och taken på råpoängen. Dagar och veckor räknas i UTC
+// utifrån när aktiviteten skedde, så en backfill hamnar under rätt dags tak.
+func (e *Engine) applyCaps(userID int64, rule *models.ScoringRule, raw int, at time.Time) (int, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	day := dayStart(at)
+	week := weekStart(at)
+	points := raw
+
+	if rule.DiminishingFactor != nil || rule.DailyCap != nil {
+		awarded, count, err := e.ActivityRepo.SumPoints(userID, rule.ActivityType, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return 0, err
+		}
+		if rule.DiminishingFactor != nil {
+			points = int(math.Round(float64(points) * math.Pow(*rule.DiminishingFactor, float64(count))))
+		}
+		if rule.DailyCap != nil {
+			points = capAt(points, *rule.DailyCap-awarded)
+		}
+	}
+
+	if rule.WeeklyCap != nil {
+		awarded, _, err := e.ActivityRepo.SumPoints(userID, rule.ActivityType, week, week.AddDate(0, 0, 7))
+		if err != nil {
+			return 0, err
+		}
+		points = capAt(points, *rule.WeeklyCap-awarded)
+	}
+
+	if e.Caps.DailyCap > 0 {
+		awarded, _, err := e.ActivityRepo.SumPoints(userID, "", day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return 0, err
+		}
+		points = capAt(points, e.Caps.DailyCap-awarded)
+	}
+
+	if e.Caps.WeeklyCap > 0 {
+		awarded, _, err := e.ActivityRepo.SumPoints(userID, "", week, week.AddDate(0, 0, 7))
+		if err != nil {
+			return 0, err
+		}
+		points = capAt(points, e.Caps.WeeklyCap-awarded)
+	}
+
+	return points, nil
+}
+
+// capAt begränsar poängen till det som är kvar under ett tak, men aldrig under 0.
+func capAt(points, remaining int) int {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if points > remaining {
+		return remaining
+	}
+	return points
+}
+
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart är måndagen i veckan som t ligger i.
+func weekStart(t time.Time) time.Time {
+	day := dayStart(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
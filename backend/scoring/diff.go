@@ -0,0 +1,194 @@
+package scoring
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"gamification-api/backend/models"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Vikter för strukturella tillägg, i samma enhet som ändrade tecken så att
+// trösklarna i poängreglerna fortsätter att betyda ungefär samma sak.
+const (
+	headingWeight   = 50
+	tableWeight     = 200
+	codeBlockWeight = 150
+	imageWeight     = 100
+)
+
+// maxDiffCells begränsar ordjämförelsen (LCS). Större ändringar jämförs som mängder av ord istället.
+const maxDiffCells = 1000000
+
+var (
+	cdataPattern     = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+	parameterPattern = regexp.MustCompile(`(?s)<ac:parameter[^>]*>.*?</ac:parameter>`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+
+	storageHeadings = regexp.MustCompile(`<h[1-6][\s>]`)
+	storageTables   = regexp.MustCompile(`<table[\s>]`)
+	storageCode     = regexp.MustCompile(`<ac:structured-macro[^>]*ac:name="(?:code|noformat)"`)
+	storageImages   = regexp.MustCompile(`<ac:image[\s>]|<img[\s>]`)
+
+	// Markdown och AsciiDoc
+	textHeadings = regexp.MustCompile(`(?m)^(?:#{1,6}|={1,6})\s`)
+	textTables   = regexp.MustCompile(`(?m)^\s*\|?\s*:?-{3,}:?\s*\||^\|===`)
+	textCode     = regexp.MustCompile("(?m)^(?:```|~~~|----$)")
+	textImages   = regexp.MustCompile(`!\[[^\]]*\]\(|image::?\S`)
+)
+
+// AnalyzeStorage jämför två versioner av en Confluence-sida i storage-format. Texten jämförs ord
+// för ord efter att formateringen har tagits bort, så omformatering ger inga poäng men en
+// omskriven paragraf gör det även om längden är densamma.
+func AnalyzeStorage(oldStorage, newStorage string) *models.DiffBreakdown {
+	oldStorage = cdataPattern.ReplaceAllStringFunc(oldStorage, escapeCDATA)
+	newStorage = cdataPattern.ReplaceAllStringFunc(newStorage, escapeCDATA)
+
+	b := diffWords(VisibleText(oldStorage), VisibleText(newStorage))
+	b.HeadingsAdded = added(storageHeadings, oldStorage, newStorage)
+	b.TablesAdded = added(storageTables, oldStorage, newStorage)
+	b.CodeBlocksAdded = added(storageCode, oldStorage, newStorage)
+	b.ImagesAdded = added(storageImages, oldStorage, newStorage)
+	b.Size = size(b)
+	return b
+}
+
+// AnalyzeText jämför två versioner av ett Markdown- eller AsciiDoc-dokument.
+func AnalyzeText(oldText, newText string) *models.DiffBreakdown {
+	b := diffWords(oldText, newText)
+	b.HeadingsAdded = added(textHeadings, oldText, newText)
+	b.TablesAdded = added(textTables, oldText, newText)
+	// Kodblock har en start- och en slutmarkering
+	b.CodeBlocksAdded = added(textCode, oldText, newText) / 2
+	b.ImagesAdded = added(textImages, oldText, newText)
+	b.Size = size(b)
+	return b
+}
+
+// VisibleText tar bort taggar och makroparametrar ur storage-formatet och avkodar entiteter.
+func VisibleText(storage string) string {
+	text := parameterPattern.ReplaceAllString(storage, " ")
+	text = tagPattern.ReplaceAllString(text, " ")
+	return html.UnescapeString(text)
+}
+
+// escapeCDATA gör om en CDATA-sektion (t.ex. innehållet i ett kodmakro) till vanlig text
+// så att den räknas som synlig text och inte tas bort med taggarna.
+func escapeCDATA(section string) string {
+	return html.EscapeString(cdataPattern.FindStringSubmatch(section)[1])
+}
+
+// ContentHash är en hash av den synliga texten i storage-formatet. Två versioner med samma
+// text (oavsett formatering och blanksteg) får samma hash.
+func ContentHash(storage string) string {
+	sum := sha1.Sum([]byte(strings.Join(strings.Fields(VisibleText(storage)), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+func added(pattern *regexp.Regexp, oldText, newText string) int {
+	n := len(pattern.FindAllStringIndex(newText, -1)) - len(pattern.FindAllStringIndex(oldText, -1))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// size är ändringens storlek i tecken: tillagda och borttagna ord plus vikterna för struktur.
+func size(b *models.DiffBreakdown) int {
+	return b.CharsInserted + b.CharsDeleted +
+		b.HeadingsAdded*headingWeight +
+		b.TablesAdded*tableWeight +
+		b.CodeBlocksAdded*codeBlockWeight +
+		b.ImagesAdded*imageWeight
+}
+
+// diffWords räknar tillagda och borttagna ord mellan två texter.
+func diffWords(oldText, newText string) *models.DiffBreakdown {
+	a, b := strings.Fields(oldText), strings.Fields(newText)
+
+	// Gemensam början och slut behöver inte jämföras
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	var inserted, deleted []string
+	if len(a)*len(b) <= maxDiffCells {
+		inserted, deleted = lcsDiff(a, b)
+	} else {
+		inserted, deleted = bagDiff(a, b)
+	}
+
+	result := &models.DiffBreakdown{WordsInserted: len(inserted), WordsDeleted: len(deleted)}
+	for _, w := range inserted {
+		result.CharsInserted += len([]rune(w)) + 1
+	}
+	for _, w := range deleted {
+		result.CharsDeleted += len([]rune(w)) + 1
+	}
+	return result
+}
+
+// lcsDiff tar fram de ord som inte ingår i den längsta gemensamma delsekvensen.
+func lcsDiff(a, b []string) (inserted, deleted []string) {
+	// lcs[i][j] är längden av LCS för a[i:] och b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			deleted = append(deleted, a[i])
+			i++
+		default:
+			inserted = append(inserted, b[j])
+			j++
+		}
+	}
+	deleted = append(deleted, a[i:]...)
+	inserted = append(inserted, b[j:]...)
+	return inserted, deleted
+}
+
+// bagDiff jämför orden som mängder utan hänsyn till ordningen. Används för mycket stora ändringar.
+func bagDiff(a, b []string) (inserted, deleted []string) {
+	counts := make(map[string]int)
+	for _, w := range a {
+		counts[w]++
+	}
+	for _, w := range b {
+		if counts[w] > 0 {
+			counts[w]--
+		} else {
+			inserted = append(inserted, w)
+		}
+	}
+	for _, w := range a {
+		if counts[w] > 0 {
+			counts[w]--
+			deleted = append(deleted, w)
+		}
+	}
+	return inserted, deleted
+}
@@ -0,0 +1,114 @@
+package scoring
+
+import (
+	"gamification-api/backend/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     models.DiffBreakdown
+	}{
+		{"oförändrad", "a b c", "a b c", models.DiffBreakdown{}},
+		{"blanksteg räknas inte", "a  b\nc", "a b c", models.DiffBreakdown{}},
+		{"utbytt ord", "the quick fox", "the slow fox",
+			models.DiffBreakdown{WordsInserted: 1, WordsDeleted: 1, CharsInserted: 5, CharsDeleted: 6}},
+		{"ny text", "", "hello world",
+			models.DiffBreakdown{WordsInserted: 2, CharsInserted: 12}},
+		{"tecken räknas som runor", "å ä", "",
+			models.DiffBreakdown{WordsDeleted: 2, CharsDeleted: 4}},
+		{"omkastade ord", "a b", "b a",
+			models.DiffBreakdown{WordsInserted: 1, WordsDeleted: 1, CharsInserted: 2, CharsDeleted: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffWords(tt.old, tt.new); *got != tt.want {
+				t.Errorf("diffWords(%q, %q) = %+v, want %+v", tt.old, tt.new, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBagDiff(t *testing.T) {
+	inserted, deleted := bagDiff([]string{"a", "b", "a"}, []string{"a", "c"})
+	if !reflect.DeepEqual(inserted, []string{"c"}) || !reflect.DeepEqual(deleted, []string{"a", "b"}) {
+		t.Errorf("bagDiff = %v, %v, want [c], [a b]", inserted, deleted)
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     models.DiffBreakdown
+	}{
+		{"rubrik", "intro", "intro\n# Title",
+			models.DiffBreakdown{WordsInserted: 2, CharsInserted: 8, HeadingsAdded: 1, Size: 58}},
+		{"kodblock räknas en gång", "x", "x\n```\ny\n```",
+			models.DiffBreakdown{WordsInserted: 3, CharsInserted: 10, CodeBlocksAdded: 1, Size: 160}},
+		{"bild", "", "![alt](a.png)",
+			models.DiffBreakdown{WordsInserted: 1, CharsInserted: 14, ImagesAdded: 1, Size: 114}},
+		{"borttagen rubrik ger inget avdrag", "# T", "",
+			models.DiffBreakdown{WordsDeleted: 2, CharsDeleted: 4, Size: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnalyzeText(tt.old, tt.new); *got != tt.want {
+				t.Errorf("AnalyzeText(%q, %q) = %+v, want %+v", tt.old, tt.new, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeStorage(t *testing.T) {
+	code := `<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter>` +
+		`<ac:plain-text-body><![CDATA[x := 1]]></ac:plain-text-body></ac:structured-macro>`
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     models.DiffBreakdown
+	}{
+		{"bara formatering", "<p>Hello world</p>", "<p><strong>Hello</strong> world</p>", models.DiffBreakdown{}},
+		{"rubrik", "<p>a</p>", "<p>a</p><h2>Rubrik</h2>",
+			models.DiffBreakdown{WordsInserted: 1, CharsInserted: 7, HeadingsAdded: 1, Size: 57}},
+		{"kodmakro räknar CDATA men inte parametrar", "", code,
+			models.DiffBreakdown{WordsInserted: 3, CharsInserted: 7, CodeBlocksAdded: 1, Size: 157}},
+		{"entiteter avkodas", "<p>a &amp; b</p>", "<p>a & b</p>", models.DiffBreakdown{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AnalyzeStorage(tt.old, tt.new); *got != tt.want {
+				t.Errorf("AnalyzeStorage(%q, %q) = %+v, want %+v", tt.old, tt.new, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	base := ContentHash("<p>Hello  world</p>")
+
+	tests := []struct {
+		name    string
+		storage string
+		same    bool
+	}{
+		{"annan formatering", "<h1>Hello</h1>\n<p>world</p>", true},
+		{"makroparametrar syns inte", `<p>Hello <ac:parameter ac:name="x">dolt</ac:parameter>world</p>`, true},
+		{"annan text", "<p>Hello there</p>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentHash(tt.storage) == base; got != tt.same {
+				t.Errorf("ContentHash(%q) == ContentHash(<p>Hello  world</p>) is %v, want %v", tt.storage, got, tt.same)
+			}
+		})
+	}
+}
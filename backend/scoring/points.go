@@ -0,0 +1,93 @@
+// Package scoring innehåller poängberäkningen som delas av alla aktivitetskällor.
+package scoring
+
+import (
+	"gamification-api/backend/config"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"log"
+	"time"
+)
+
+var basePoints int = 11
+
+// diffThresholds är standardgränserna för hur stor en ändring är, i tecken.
+var diffThresholds = []int{100, 400, 700, 1000, 1300, 1600, 1900, 2200}
+
+// DefaultRules är reglerna som seedas in i scoring_rules och som används om en aktivitetstyp
+// saknar aktiv regel i databasen.
+func DefaultRules() []models.ScoringRule {
+	return []models.ScoringRule{
+		{ActivityType: models.ActivityTypePageCreated, BasePoints: basePoints},
+		{ActivityType: models.ActivityTypePageUpdated, BasePoints: basePoints, DiffThresholds: diffThresholds},
+		{ActivityType: models.ActivityTypeCommentCreated, BasePoints: 2 * basePoints},
+		{ActivityType: models.ActivityTypeResolvedComment, BasePoints: 3 * basePoints},
+		{ActivityType: models.ActivityTypeDocCreated, BasePoints: basePoints},
+		{ActivityType: models.ActivityTypeDocUpdated, BasePoints: basePoints, DiffThresholds: diffThresholds},
+	}
+}
+
+// Result är poängen för en aktivitet och regelversionen som gav dem. RawPoints är poängen enligt
+// regeln och Points det som faktiskt delas ut efter tak och avtagande poäng.
+// RuleID är nil om standardregeln i koden användes.
+type Result struct {
+	Points    int
+	RawPoints int
+	RuleID    *int64
+}
+
+// Engine räknar ut poäng med de aktiva reglerna i databasen. Reglerna läses vid varje
+// anrop, så ändringar via API:et gäller direkt.
+type Engine struct {
+	Repo *database.ScoringRuleRepository
+	// ActivityRepo och Caps behövs för taken. Utan ActivityRepo delas råpoängen ut.
+	ActivityRepo *database.ActivityRepository
+	Caps         config.PointCapConfig
+}
+
+// Award räknar ut poängen som userID ska få för en aktivitet som skedde vid tidpunkten at,
+// med regelns avtagande poäng och dags-/veckotak samt de globala taken.
+func (e *Engine) Award(userID int64, activityType string, diff int, at time.Time) Result {
+	rule := e.activeRule(activityType)
+	raw := rule.Points(diff)
+	result := Result{Points: raw, RawPoints: raw, RuleID: ruleID(rule)}
+	if e.ActivityRepo == nil || raw <= 0 {
+		return result
+	}
+
+	points, err := e.applyCaps(userID, rule, raw, at)
+	if err != nil {
+		log.Printf("FEL vid kontroll av poängtak för user %d, delar ut råpoängen: %v", userID, err)
+		return result
+	}
+	result.Points = points
+	return result
+}
+
+func (e *Engine) activeRule(activityType string) *models.ScoringRule {
+	rule, err := e.Repo.GetActiveRule(activityType)
+	if err != nil {
+		log.Printf("FEL vid hämtning av poängregel för %s, använder standardregeln: %v", activityType, err)
+	}
+	if rule == nil {
+		return defaultRule(activityType)
+	}
+	return rule
+}
+
+// ruleID är nil för standardreglerna i koden, som inte finns i databasen.
+func ruleID(rule *models.ScoringRule) *int64 {
+	if rule.ID == 0 {
+		return nil
+	}
+	return &rule.ID
+}
+
+func defaultRule(activityType string) *models.ScoringRule {
+	for _, rule := range DefaultRules() {
+		if rule.ActivityType == activityType {
+			return &rule
+		}
+	}
+	return &models.ScoringRule{ActivityType: activityType}
+}
@@ -0,0 +1,102 @@
+package scoring
+
+import (
+	"gamification-api/backend/models"
+	"testing"
+	"time"
+)
+
+func TestDefaultRulePoints(t *testing.T) {
+	tests := []struct {
+		activityType string
+		diff         int
+		want         int
+	}{
+		{models.ActivityTypePageCreated, 5000, 11},
+		{models.ActivityTypePageUpdated, 0, 11},
+		{models.ActivityTypePageUpdated, 100, 11},
+		{models.ActivityTypePageUpdated, 101, 22},
+		{models.ActivityTypeDocUpdated, 1000, 44},
+		{models.ActivityTypePageUpdated, 2200, 88},
+		{models.ActivityTypePageUpdated, 2201, 99},
+		{models.ActivityTypeCommentCreated, 5000, 22},
+		{models.ActivityTypeResolvedComment, 0, 33},
+		{"UNKNOWN", 500, 0},
+	}
+
+	for _, tt := range tests {
+		if got := defaultRule(tt.activityType).Points(tt.diff); got != tt.want {
+			t.Errorf("defaultRule(%s).Points(%d) = %d, want %d", tt.activityType, tt.diff, got, tt.want)
+		}
+	}
+}
+
+func TestRulePointsMax(t *testing.T) {
+	max := 50
+	rule := &models.ScoringRule{BasePoints: 11, DiffThresholds: diffThresholds, MaxPoints: &max}
+
+	tests := []struct {
+		diff int
+		want int
+	}{
+		{100, 11},
+		{1000, 44},
+		{1300, 50},
+		{5000, 50},
+	}
+
+	for _, tt := range tests {
+		if got := rule.Points(tt.diff); got != tt.want {
+			t.Errorf("Points(%d) = %d, want %d", tt.diff, got, tt.want)
+		}
+	}
+}
+
+func TestRuleID(t *testing.T) {
+	if id := ruleID(defaultRule(models.ActivityTypePageCreated)); id != nil {
+		t.Errorf("ruleID for default rule = %d, want nil", *id)
+	}
+	if id := ruleID(&models.ScoringRule{ID: 7}); id == nil || *id != 7 {
+		t.Errorf("ruleID = %v, want 7", id)
+	}
+}
+
+func TestCapAt(t *testing.T) {
+	tests := []struct {
+		points, remaining, want int
+	}{
+		{10, 20, 10},
+		{10, 5, 5},
+		{10, 0, 0},
+		{10, -3, 0},
+	}
+
+	for _, tt := range tests {
+		if got := capAt(tt.points, tt.remaining); got != tt.want {
+			t.Errorf("capAt(%d, %d) = %d, want %d", tt.points, tt.remaining, got, tt.want)
+		}
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"måndag", time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), monday},
+		{"onsdag", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC), monday},
+		{"söndag", time.Date(2024, 1, 7, 23, 59, 0, 0, time.UTC), monday},
+		{"räknas i UTC", time.Date(2024, 1, 8, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)), monday},
+		{"nästa vecka", time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), monday.AddDate(0, 0, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weekStart(tt.at); !got.Equal(tt.want) {
+				t.Errorf("weekStart(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
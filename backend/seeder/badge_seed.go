@@ -12,19 +12,29 @@ func SeedBadges(db *sql.DB) error {
 	badgeData := []struct {
 		Name, Description, IconURL, CriteriaType string
 		CriteriaValue                            int
+		Series                                   string
+		Tier                                     int
 	}{
-		{"Beginner documenter", "Awarded for creating your very first document.", "documents0", "total_created_pages", 1},
-		{"Novice documenter", "Awarded for creating your 10th document.", "documents1", "total_created_pages", 10},
-		{"Intermidiate documenter", "Awarded for creating your 50th document.", "documents2", "total_created_pages", 50},
-		{"Proffesional documenter", "Awarded for creating your 100th document.", "documents3", "total_created_pages", 100},
-		{"Beginner commenter", "Awarded for making your very first comment.", "comments0", "total_comments", 1},
-		{"Novice commenter", "Awarded for making your 10th comment.", "comments1", "total_comments", 10},
-		{"Intermidiate commenter", "Awarded for mmaking your 50th comment.", "comments2", "total_comments", 50},
-		{"Proffesional commenter", "Awarded for making your 100th comment.", "comments3", "total_comments", 100},
-		{"Beginner editor", "Awarded for making your very first edit.", "edits0", "total_edits_made", 1},
-		{"Novice editor", "Awarded for making your 10th edit.", "edits1", "total_edits_made", 10},
-		{"Intermidiate editor", "Awarded for making your 50th edit.", "edits2", "total_edits_made", 50},
-		{"Proffesional editor", "Awarded for making your 100th edit.", "edits3", "total_edits_made", 100},
+		{"Beginner documenter", "Awarded for creating your very first document.", "documents0", "total_created_pages", 1, "documenter", 1},
+		{"Novice documenter", "Awarded for creating your 10th document.", "documents1", "total_created_pages", 10, "documenter", 2},
+		{"Intermidiate documenter", "Awarded for creating your 50th document.", "documents2", "total_created_pages", 50, "documenter", 3},
+		{"Proffesional documenter", "Awarded for creating your 100th document.", "documents3", "total_created_pages", 100, "documenter", 4},
+		{"Beginner commenter", "Awarded for making your very first comment.", "comments0", "total_comments", 1, "commenter", 1},
+		{"Novice commenter", "Awarded for making your 10th comment.", "comments1", "total_comments", 10, "commenter", 2},
+		{"Intermidiate commenter", "Awarded for mmaking your 50th comment.", "comments2", "total_comments", 50, "commenter", 3},
+		{"Proffesional commenter", "Awarded for making your 100th comment.", "comments3", "total_comments", 100, "commenter", 4},
+		{"Beginner editor", "Awarded for making your very first edit.", "edits0", "total_edits_made", 1, "editor", 1},
+		{"Novice editor", "Awarded for making your 10th edit.", "edits1", "total_edits_made", 10, "editor", 2},
+		{"Intermidiate editor", "Awarded for making your 50th edit.", "edits2", "total_edits_made", 50, "editor", 3},
+		{"Proffesional editor", "Awarded for making your 100th edit.", "edits3", "total_edits_made", 100, "editor", 4},
+		{"Steady contributor", "Awarded for contributing 7 days in a row.", "streaks0", "daily_streak", 7, "daily_streak", 1},
+		{"Dedicated contributor", "Awarded for contributing 30 days in a row.", "streaks1", "daily_streak", 30, "daily_streak", 2},
+		{"Regular contributor", "Awarded for contributing 4 weeks in a row.", "streaks2", "weekly_streak", 4, "weekly_streak", 1},
+		{"Devoted contributor", "Awarded for contributing 26 weeks in a row.", "streaks3", "weekly_streak", 26, "weekly_streak", 2},
+		// Manuella badges delas ut av en admin, direkt eller efter en nominering. Målet är 1 så att
+		// frontendens progress >= criteriaValue bara visar dem som upplåsta när de har delats ut
+		{"Great explainer", "Awarded for explaining things so that everyone understands.", "explainer", "manual", 1, "", 0},
+		{"Helping hand", "Awarded for going out of your way to help a colleague.", "helper", "manual", 1, "", 0},
 	}
 
 	tx, err := db.Begin()
@@ -34,13 +44,15 @@ func SeedBadges(db *sql.DB) error {
 	defer tx.Rollback() // Rulla tillbaka om något går fel
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO badges (name, description, icon_url, criteria_value, criteria_type)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO badges (name, description, icon_url, criteria_value, criteria_type, series, tier)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (name) DO UPDATE SET
 			description = EXCLUDED.description,
 			icon_url = EXCLUDED.icon_url,
 			criteria_value = EXCLUDED.criteria_value,
-			criteria_type = EXCLUDED.criteria_type
+			criteria_type = EXCLUDED.criteria_type,
+			series = EXCLUDED.series,
+			tier = EXCLUDED.tier
 	`)
 	if err != nil {
 		return fmt.Errorf("kunde inte förbereda badge-upsert: %w", err)
@@ -49,7 +61,7 @@ func SeedBadges(db *sql.DB) error {
 
 	fmt.Println("🏅 Synkroniserar badges...")
 	for _, b := range badgeData {
-		if _, err := stmt.Exec(b.Name, b.Description, b.IconURL, b.CriteriaValue, b.CriteriaType); err != nil {
+		if _, err := stmt.Exec(b.Name, b.Description, b.IconURL, b.CriteriaValue, b.CriteriaType, b.Series, b.Tier); err != nil {
 			// Om ett fel inträffar här, rullas transaktionen tillbaka
 			return fmt.Errorf("kunde inte infoga/uppdatera badge %s: %w", b.Name, err)
 		}
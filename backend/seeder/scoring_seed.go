@@ -0,0 +1,33 @@
+package seeder
+
+import (
+	"database/sql"
+	"fmt"
+	"gamification-api/backend/scoring"
+
+	"github.com/lib/pq"
+)
+
+// SeedScoringRules lägger in standardreglerna för aktivitetstyper som inte har någon poängregel än.
+// Regler som en admin har ändrat lämnas orörda.
+func SeedScoringRules(db *sql.DB) error {
+	fmt.Println("🎯 Kontrollerar poängregler...")
+
+	for _, rule := range scoring.DefaultRules() {
+		thresholds := make(pq.Int64Array, len(rule.DiffThresholds))
+		for i, t := range rule.DiffThresholds {
+			thresholds[i] = int64(t)
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO scoring_rules (activity_type, version, base_points, diff_thresholds, active)
+			SELECT $1, 1, $2, $3, TRUE
+			WHERE NOT EXISTS (SELECT 1 FROM scoring_rules WHERE activity_type = $1)`,
+			rule.ActivityType, rule.BasePoints, thresholds)
+		if err != nil {
+			return fmt.Errorf("kunde inte seeda poängregel för %s: %w", rule.ActivityType, err)
+		}
+	}
+
+	return nil
+}
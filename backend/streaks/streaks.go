@@ -0,0 +1,85 @@
+// Package streaks räknar ut hur många dagar och veckor i rad användare har bidragit.
+package streaks
+
+import (
+	"gamification-api/backend/config"
+	"gamification-api/backend/database"
+	"gamification-api/backend/models"
+	"time"
+)
+
+// Tracker räknar ut sviter från aktiviteterna. Dagar räknas i UTC och veckor börjar på måndag,
+// samma som poängtaken.
+type Tracker struct {
+	Repo   *database.ActivityRepository
+	Config config.StreakConfig
+}
+
+// ForUser räknar ut användarens sviter, eventuellt bara i ett space.
+func (t *Tracker) ForUser(userID int64, spaceKey string) (*models.UserStreaks, error) {
+	days, err := t.Repo.GetActiveDays(userID, spaceKey)
+	if err != nil {
+		return nil, err
+	}
+	return Compute(days, time.Now(), t.Config), nil
+}
+
+// Compute räknar ut sviterna för de aktiva dagarna (sorterade, äldst först) vid tidpunkten now.
+// Dagens och veckans period är inte slut än, så en svit är fortfarande aktuell om bara
+// perioderna efter den senaste aktiviteten och fram till nu ryms inom karenstiden.
+func Compute(days []time.Time, now time.Time, cfg config.StreakConfig) *models.UserStreaks {
+	var dayIndexes, weekIndexes []int
+	for _, d := range days {
+		i := dayIndex(d)
+		dayIndexes = append(dayIndexes, i)
+		if w := weekIndex(i); len(weekIndexes) == 0 || weekIndexes[len(weekIndexes)-1] != w {
+			weekIndexes = append(weekIndexes, w)
+		}
+	}
+
+	today := dayIndex(now)
+	daily := compute(dayIndexes, today, cfg.DailyGraceDays)
+	weekly := compute(weekIndexes, weekIndex(today), cfg.WeeklyGraceWeeks)
+	if len(dayIndexes) > 0 {
+		last := dayIndexes[len(dayIndexes)-1]
+		daily.LastActive = dayStart(last)
+		weekly.LastActive = dayStart(weekIndex(last)*7 - 3)
+	}
+	return &models.UserStreaks{Daily: daily, Weekly: weekly}
+}
+
+// compute räknar ut den aktuella och längsta sviten för sorterade periodindex.
+func compute(periods []int, now, grace int) models.Streak {
+	var s models.Streak
+	run := 0
+	for i, p := range periods {
+		if i > 0 && p-periods[i-1]-1 <= grace {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+	}
+	if len(periods) > 0 && now-periods[len(periods)-1]-1 <= grace {
+		s.Current = run
+	}
+	return s
+}
+
+// dayIndex är antalet dagar sedan 1970-01-01 (UTC).
+func dayIndex(t time.Time) int {
+	return int(t.UTC().Unix() / 86400)
+}
+
+// weekIndex är veckan som dagen ligger i. 1970-01-01 var en torsdag, så veckorna räknas
+// från måndagen 1969-12-29.
+func weekIndex(day int) int {
+	return (day + 3) / 7
+}
+
+func dayStart(day int) *time.Time {
+	t := time.Unix(int64(day)*86400, 0).UTC()
+	return &t
+}
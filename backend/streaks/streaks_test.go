@@ -0,0 +1,115 @@
+package streaks
+
+import (
+	"gamification-api/backend/config"
+	"gamification-api/backend/models"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCompute(t *testing.T) {
+	// Onsdag, veckan börjar måndag 2024-01-08
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		days              []time.Time
+		cfg               config.StreakConfig
+		daily, weekly     models.Streak
+		lastDay, lastWeek time.Time
+	}{
+		{
+			name: "inga aktiviteter",
+		},
+		{
+			name:    "dagar i rad till idag",
+			days:    []time.Time{day(2024, 1, 8), day(2024, 1, 9), day(2024, 1, 10)},
+			daily:   models.Streak{Current: 3, Longest: 3},
+			weekly:  models.Streak{Current: 1, Longest: 1},
+			lastDay: day(2024, 1, 10), lastWeek: day(2024, 1, 8),
+		},
+		{
+			name:    "dagens period är inte slut",
+			days:    []time.Time{day(2024, 1, 7), day(2024, 1, 8), day(2024, 1, 9)},
+			daily:   models.Streak{Current: 3, Longest: 3},
+			weekly:  models.Streak{Current: 2, Longest: 2},
+			lastDay: day(2024, 1, 9), lastWeek: day(2024, 1, 8),
+		},
+		{
+			name:    "bruten svit",
+			days:    []time.Time{day(2024, 1, 1), day(2024, 1, 2), day(2024, 1, 3), day(2024, 1, 8)},
+			daily:   models.Streak{Current: 0, Longest: 3},
+			weekly:  models.Streak{Current: 2, Longest: 2},
+			lastDay: day(2024, 1, 8), lastWeek: day(2024, 1, 8),
+		},
+		{
+			name:    "karensdagar",
+			days:    []time.Time{day(2024, 1, 5), day(2024, 1, 7), day(2024, 1, 9)},
+			cfg:     config.StreakConfig{DailyGraceDays: 1},
+			daily:   models.Streak{Current: 3, Longest: 3},
+			weekly:  models.Streak{Current: 2, Longest: 2},
+			lastDay: day(2024, 1, 9), lastWeek: day(2024, 1, 8),
+		},
+		{
+			name:    "missad vecka utan karens",
+			days:    []time.Time{day(2023, 12, 18), day(2024, 1, 2), day(2024, 1, 9)},
+			daily:   models.Streak{Current: 1, Longest: 1},
+			weekly:  models.Streak{Current: 2, Longest: 2},
+			lastDay: day(2024, 1, 9), lastWeek: day(2024, 1, 8),
+		},
+		{
+			name:    "missad vecka med karens",
+			days:    []time.Time{day(2023, 12, 18), day(2024, 1, 2), day(2024, 1, 9)},
+			cfg:     config.StreakConfig{WeeklyGraceWeeks: 1},
+			daily:   models.Streak{Current: 1, Longest: 1},
+			weekly:  models.Streak{Current: 3, Longest: 3},
+			lastDay: day(2024, 1, 9), lastWeek: day(2024, 1, 8),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.days, now, tt.cfg)
+			checkStreak(t, "daily", got.Daily, tt.daily, tt.lastDay)
+			checkStreak(t, "weekly", got.Weekly, tt.weekly, tt.lastWeek)
+		})
+	}
+}
+
+func checkStreak(t *testing.T, kind string, got, want models.Streak, lastActive time.Time) {
+	t.Helper()
+	if got.Current != want.Current || got.Longest != want.Longest {
+		t.Errorf("%s = current %d, longest %d, want current %d, longest %d",
+			kind, got.Current, got.Longest, want.Current, want.Longest)
+	}
+	switch {
+	case lastActive.IsZero() && got.LastActive != nil:
+		t.Errorf("%s lastActive = %v, want nil", kind, *got.LastActive)
+	case !lastActive.IsZero() && (got.LastActive == nil || !got.LastActive.Equal(lastActive)):
+		t.Errorf("%s lastActive = %v, want %v", kind, got.LastActive, lastActive)
+	}
+}
+
+func TestWeekIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b time.Time
+		same bool
+	}{
+		{"måndag och söndag", day(2024, 1, 8), day(2024, 1, 14), true},
+		{"söndag och måndag", day(2024, 1, 7), day(2024, 1, 8), false},
+		{"runt 1970-01-01", day(1969, 12, 29), day(1970, 1, 4), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weekIndex(dayIndex(tt.a)) == weekIndex(dayIndex(tt.b)); got != tt.same {
+				t.Errorf("same week for %v and %v = %v, want %v", tt.a, tt.b, got, tt.same)
+			}
+		})
+	}
+}